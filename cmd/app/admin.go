@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runCreateAdmin create a super user and exit, usage:
+//
+//	main create-admin -username admin@example.com -name Admin < password.txt
+//
+// the password is read from the first line of stdin so it is not kept in the shell history
+func runCreateAdmin(ctx context.Context, args []string) int {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the admin")
+	fullName := flags.String("name", "Admin", "full name of the admin")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	if *username == "" {
+		fmt.Fprintln(os.Stderr, "usage: create-admin -username <username> [-name <full name>] < password")
+		return 2
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		logrus.Errorf("empty password on stdin: %v", err)
+		return 2
+	}
+
+	code, response := newApp().UserService.CreateUser(ctx, models.User{
+		Username: *username,
+		Password: password,
+		FullName: *fullName,
+		Status:   helper.UserActive,
+		Level:    helper.UserLevelAdmin,
+	})
+	if code != http.StatusCreated {
+		logrus.Errorf("failed to create admin %s : %s", *username, response.Error.Message.EN)
+		return 1
+	}
+
+	logrus.Infof("admin %s created", *username)
+
+	return 0
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/services"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// App entry point of every command built from one graph, so a process open a single connection to every backend
+type App struct {
+	Router         *gin.Engine
+	Consumer       *AmqpController
+	UserService    services.IUserService
+	Reconciliation services.IReconciliationService
+	DeadLetter     services.IDeadLetterService
+	Lifecycle      *lifecycle.Manager
+	Master         gorm.IGormMaster
+	Slave          gorm.IGormSlave
+	Redis          redis.Iredis
+}
+
+func newApp() *App {
+	return InitializedApp(
+		config.BuildMasterDBParam(),
+		config.BuildSlaveDBParam(),
+		config.BuildRedisParam(),
+		config.BuildRabbitMQParam(),
+		config.BuildLifecycleParam(),
+	)
+}
+
+// process part of the app run by a long running command
+type process struct {
+	serve   bool
+	consume bool
+	// queues consumed, every registered queue when empty
+	queues []string
+	relay  bool
+}
+
+// runProcess run the server, the consumer and the outbox relay of the process until SIGINT or SIGTERM. On stop the
+// process is not ready anymore, then the server, the consumer, the outbox relay, the broker connection, then the redis
+// and the database connection stop in that order
+func runProcess(ctx context.Context, p process) int {
+	cfg := config.Get()
+
+	app := newApp()
+
+	manager := app.Lifecycle
+	manager.Append(
+		closeHook("database master", app.Master.Close),
+		// close the replica and stop their health check
+		closeHook("database replica", app.Slave.Close),
+		closeHook("redis", app.Redis.Close),
+		lifecycle.Hook{Name: "message broker", Stop: app.Consumer.Amqp.Close},
+	)
+
+	if p.relay {
+		manager.Append(lifecycle.Background("outbox relay", 0, func(ctx context.Context) {
+			app.Consumer.StartOutboxRelay(ctx, cfg)
+		}))
+	}
+
+	if p.consume {
+		hook, err := app.Consumer.ConsumerHook(cfg, p.queues)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return 2
+		}
+		manager.Append(hook)
+	}
+
+	if p.serve {
+		// the drain stop before the server, so the server keep serving until the load balancer stop routing to it
+		manager.Append(serverHook(app.Router, cfg), lifecycle.Drain(time.Duration(cfg.Shutdown.DrainDelay)*time.Second))
+	}
+
+	err := manager.Run(ctx)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return 1
+	}
+
+	return 0
+}
+
+// serverHook listen on start so a port already in use fail the start, on stop the server finish the request in process
+func serverHook(e *gin.Engine, cfg config.ConfigStructure) lifecycle.Hook {
+	srv := &http.Server{
+		Addr:        cfg.Port,
+		Handler:     e,
+		IdleTimeout: time.Duration(cfg.ServerIdleTimeout) * time.Second,
+	}
+
+	return lifecycle.Hook{
+		Name:    "http server",
+		Timeout: time.Duration(cfg.ServerTimeout) * time.Second,
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+
+			go func() {
+				if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
+					logrus.Errorf("http server stopped serving : %s", err)
+				}
+			}()
+
+			return nil
+		},
+		Stop: srv.Shutdown,
+	}
+}
+
+// closeHook stop hook of a connection closed without context
+func closeHook(name string, close func() error) lifecycle.Hook {
+	return lifecycle.Hook{
+		Name: name,
+		Stop: func(ctx context.Context) error {
+			return close()
+		},
+	}
+}
@@ -2,186 +2,200 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/galihfebrizki/dbo-api/config"
 	"github.com/galihfebrizki/dbo-api/helper"
 	"github.com/galihfebrizki/dbo-api/internal/controllers"
+	"github.com/galihfebrizki/dbo-api/internal/services"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
 	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	log "github.com/sirupsen/logrus"
 )
 
-type Consumer struct {
-	Ctx       context.Context
-	AppName   string
-	QueueName string
-	Worker    func(ctx context.Context, d amqp.Delivery) bool
-	Requeue   bool
-}
-
 type AmqpController struct {
 	// consumer conn
 	Amqp rabbitmq.IRabbitMQ
 
-	// controllers -> add if any controller exist
-	PaymentController *controllers.PaymentController
+	// processed message store
+	Redis redis.Iredis
+
+	// registered consumer, see NewConsumers
+	Consumers []rabbitmq.Consumer
+
+	// outbox relay
+	OutboxService services.IOutboxService
 }
 
 func NewAmqpConsumer(
 	iRabbitMq rabbitmq.IRabbitMQ,
-	paymentController *controllers.PaymentController,
+	iRedis redis.Iredis,
+	consumers []rabbitmq.Consumer,
+	outboxService services.IOutboxService,
 ) *AmqpController {
 	return &AmqpController{
-		Amqp:              iRabbitMq,
-		PaymentController: paymentController,
+		Amqp:          iRabbitMq,
+		Redis:         iRedis,
+		Consumers:     consumers,
+		OutboxService: outboxService,
 	}
 }
 
-func (h *AmqpController) StartConsumer(ctx context.Context, cfg config.ConfigStructure) {
-	appName := cfg.Name
-
-	// register consumer
-	consumerList := []Consumer{
-		{
-			Ctx:       ctx,
-			AppName:   appName,
-			QueueName: helper.PaymentProccess,
-			Worker:    h.PaymentController.ConsumerPaymentProccess,
-			Requeue:   true,
-		},
-	}
-
-	go h.BindConsumer(ctx, consumerList, cfg)
-}
-
-func (h *AmqpController) BindConsumer(ctx context.Context, consumers []Consumer, cfg config.ConfigStructure) {
-	tickerBind := time.Tick(time.Duration(cfg.MessageBroker.RabbitMq.BindingTime) * time.Second)
-	consumerTag := helper.GenerateRandomString(10)
+// NewConsumers every queue consumed by the app, a new consumer is a typed handler on its controller and a line here
+func NewConsumers(
+	orderController *controllers.OrderController,
+	paymentController *controllers.PaymentController,
+	exportController *controllers.ExportController,
+	refundController *controllers.RefundController,
+	invoiceController *controllers.InvoiceController,
+) []rabbitmq.Consumer {
+	cfg := config.Get()
 
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				// Log the panic and continue running the program
-				log.WithField(helper.GetRequestIDContext(ctx)).Errorf("goroutine panicked: %v", r)
-			}
-		}()
+	backoff, err := rabbitmq.ParseBackoff(cfg.MessageBroker.RabbitMq.Backoff)
+	if err != nil {
+		log.Errorf("invalid consumer backoff %s: %s", cfg.MessageBroker.RabbitMq.Backoff, err)
+	}
 
-		for range tickerBind {
-			for _, consumer := range consumers {
-				// check consumer
-				queueURL := fmt.Sprintf("%s/api/queues/%s/%s", cfg.MessageBroker.RabbitMq.Host, "%2F", consumer.QueueName)
+	defaultRetry := rabbitmq.RetryPolicy{
+		MaxAttempt: cfg.MessageBroker.RabbitMq.MaxAttempt,
+		Backoff:    backoff,
+	}
 
-				_, isAvailable, err := h.GetConsumer(queueURL, cfg.MessageBroker.RabbitMq.Username, cfg.MessageBroker.RabbitMq.Password, consumerTag)
+	return []rabbitmq.Consumer{
+		rabbitmq.Handle(helper.PaymentProccess, paymentController.ConsumerPaymentProccess, consumerOptions(cfg, helper.PaymentProccess, defaultRetry)),
+		// failed export is not retried automatically, it can be replayed from the dead letter queue
+		rabbitmq.Handle(helper.OrderExport, exportController.ConsumerOrderExport, consumerOptions(cfg, helper.OrderExport, rabbitmq.RetryPolicy{MaxAttempt: 1})),
+		rabbitmq.Handle(helper.RefundProccess, refundController.ConsumerRefundProccess, consumerOptions(cfg, helper.RefundProccess, defaultRetry)),
+		rabbitmq.Handle(helper.InvoiceGenerate, invoiceController.ConsumerOrderPaid, consumerOptions(cfg, helper.InvoiceGenerate, defaultRetry)),
+		rabbitmq.Handle(helper.OrderActivity, orderController.ConsumerOrderActivity, consumerOptions(cfg, helper.OrderActivity, defaultRetry)),
+	}
+}
 
-				log.WithField(helper.GetRequestIDContext(ctx)).Debugf("Consumer Bind : Queue %s is %v", consumer.QueueName, isAvailable)
-				if err != nil {
-					log.WithField(helper.GetRequestIDContext(ctx)).Error(err.Error())
-					break
-				}
+// consumerOptions worker count, prefetch and rate limit of the queue from RABBITMQ_CONSUMER_<QUEUE>_*
+func consumerOptions(cfg config.ConfigStructure, queue string, retry rabbitmq.RetryPolicy) rabbitmq.ConsumerOptions {
+	consumer := cfg.MessageBroker.RabbitMq.Consumer[queue]
 
-				if !isAvailable && err == nil {
+	return rabbitmq.ConsumerOptions{
+		Concurrency: consumer.Concurrency,
+		Prefetch:    consumer.Prefetch,
+		RateLimit:   consumer.RateLimit,
+		Retry:       retry,
+	}
+}
 
-					log.WithField(helper.GetRequestIDContext(ctx)).Debugf("Started Consumer Bind : Queue %s with tag %s", consumer.QueueName, consumerTag)
-					// start consumer
-					go func(consumer Consumer) {
-						defer func() {
-							if r := recover(); r != nil {
-								// Log the panic and continue running the program
-								log.WithField(helper.GetRequestIDContext(ctx)).Errorf("goroutine panicked: %v", r)
-							}
-						}()
+// ConsumerHook run the consumer of the given queue, every registered consumer when no queue is given. On stop the
+// consumer stop receiving message and the message in process are finished until SHUTDOWN_CONSUMER_TIMEOUT, a message
+// still in process after it is not acknowledged and is delivered again by the broker
+func (h *AmqpController) ConsumerHook(cfg config.ConfigStructure, queues []string) (lifecycle.Hook, error) {
+	selected := map[string]bool{}
+	for _, queue := range queues {
+		selected[queue] = false
+	}
 
-						err := h.Amqp.ConsumeMessage(consumer.Ctx, consumer.AppName, consumer.QueueName, consumerTag, consumer.Worker, consumer.Requeue)
-						if err != nil {
-							log.WithField(helper.GetRequestIDContext(ctx)).Error(err.Error())
-						}
-					}(consumer)
+	consumers := make([]rabbitmq.Consumer, 0, len(h.Consumers))
 
-				}
-			}
+	// every delivery is processed once per message id
+	for _, consumer := range h.Consumers {
+		if _, ok := selected[consumer.Queue]; len(selected) > 0 && !ok {
+			continue
 		}
-	}()
-
-	// listen OS exit signal
-	<-helper.ExitAMQP
+		selected[consumer.Queue] = true
 
-	// trigger exit all consumer
-	for i := 0; i < helper.ConsumerCount; i++ {
-		helper.ExitConsumer <- true
+		consumer.Worker = h.Deduplicate(consumer.Queue, consumer.Worker, cfg)
+		consumers = append(consumers, consumer)
 	}
 
-	// waiting for all worker concurrency done
-	for i := 0; i < config.Get().MessageBroker.RabbitMq.Concurrency*helper.ConsumerCount; i++ {
-		<-helper.ExitConcurrency
+	for queue, registered := range selected {
+		if !registered {
+			return lifecycle.Hook{}, fmt.Errorf("no consumer registered for queue %s", queue)
+		}
 	}
 
-	// close amqp connection
-	h.Amqp.Close(ctx)
-
-	// trigger exit for http
-	helper.ExitHTTP <- true
+	return lifecycle.Background("consumer", time.Duration(cfg.Shutdown.ConsumerTimeout)*time.Second, func(ctx context.Context) {
+		h.BindConsumer(ctx, consumers, cfg)
+	}), nil
 }
 
-func (h *AmqpController) GetConsumer(queueURL, username, password, consumerTag string) (int, bool, error) {
-	// Create an HTTP client
-	client := &http.Client{}
-
-	consumerStatus := false
+// BindConsumer run every consumer under the rabbitmq supervisor, which restart the consumer whenever its channel
+// or the connection is closed, until the context is done and every consumer has finished its message in process
+func (h *AmqpController) BindConsumer(ctx context.Context, consumers []rabbitmq.Consumer, cfg config.ConfigStructure) {
+	consumerTag := helper.GenerateRandomString(10)
 
-	fmt.Println("URL :", queueURL)
+	wg := sync.WaitGroup{}
+	for _, consumer := range consumers {
+		wg.Add(1)
+		go func(consumer rabbitmq.Consumer) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					// Log the panic and continue running the program
+					log.WithField(helper.GetRequestIDContext(ctx)).Errorf("goroutine panicked: %v", r)
+				}
+			}()
 
-	// Create an HTTP request
-	req, err := http.NewRequest("GET", queueURL, nil)
-	if err != nil {
-		return 0, consumerStatus, err
+			err := h.Amqp.ConsumeMessage(ctx, cfg.Name, consumerTag, consumer)
+			if err != nil {
+				log.WithField(helper.GetRequestIDContext(ctx)).Error(err.Error())
+			}
+		}(consumer)
 	}
 
-	// Set the basic authentication credentials if required
-	req.SetBasicAuth(username, password)
+	wg.Wait()
+}
 
-	// Send the HTTP request
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, consumerStatus, err
-	}
-	defer resp.Body.Close()
+// Deduplicate wrap the worker so a message id that has been processed is acknowledged without running the worker again.
+// The message is marked processed only after the worker succeed, a failed delivery can be processed again. A delivery
+// of a message still being processed is retried instead of acknowledged.
+// When redis is not available the worker is still run
+func (h *AmqpController) Deduplicate(queue string, worker func(ctx context.Context, d amqp.Delivery) error, cfg config.ConfigStructure) func(ctx context.Context, d amqp.Delivery) error {
+	processedTTL := time.Duration(cfg.MessageBroker.RabbitMq.DedupTTL) * time.Second
+	lockTTL := time.Duration(cfg.MessageBroker.RabbitMq.DedupLockTTL) * time.Second
+
+	return func(ctx context.Context, d amqp.Delivery) error {
+		if d.MessageId == "" {
+			return worker(ctx, d)
+		}
 
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return 0, consumerStatus, err
-	}
+		key := fmt.Sprintf(helper.ProcessedMessageKey, queue, d.MessageId)
 
-	// Parse the JSON response
-	type consumerDetail struct {
-		ConsumerTag string `json:"consumer_tag"`
-	}
+		isNew, err := h.Redis.SetNX(ctx, key, helper.MessageProcessing, lockTTL)
+		if err != nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Errorf("dedup store error, message id %s processed without dedup: %s", d.MessageId, err)
+			return worker(ctx, d)
+		}
 
-	var queueInfo struct {
-		Consumers       int              `json:"consumers"`
-		ConsumerDetails []consumerDetail `json:"consumer_details"`
-	}
-	err = json.Unmarshal(body, &queueInfo)
-	if err != nil {
-		return 0, consumerStatus, err
-	}
+		if !isNew {
+			var state string
+			err = h.Redis.Get(ctx, key, &state)
+			if err == nil && state == helper.MessageProcessed {
+				log.WithField(helper.GetRequestIDContext(ctx)).Infof("duplicate message id %s on %s is skipped", d.MessageId, queue)
+				return nil
+			}
 
-	for _, consumer := range queueInfo.ConsumerDetails {
-		consumerUnique := strings.Split(consumer.ConsumerTag, "|")
-		consumerUniqueLen := len(consumerUnique)
-		consumerId := consumerUnique[consumerUniqueLen-1]
+			// still processed by another delivery, or by a worker that died, the delivery go back through the retry queue
+			// without using up an attempt so it is processed once the lock is released or expired
+			return rabbitmq.Requeue(fmt.Errorf("message id %s on %s is being processed", d.MessageId, queue))
+		}
 
-		if consumerId == consumerTag {
-			consumerStatus = true
+		workerErr := worker(ctx, d)
+		if workerErr != nil {
+			err = h.Redis.Del(ctx, key)
+			if err != nil {
+				log.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			}
+			return workerErr
 		}
-	}
 
-	return queueInfo.Consumers, consumerStatus, nil
+		err = h.Redis.Set(ctx, key, helper.MessageProcessed, processedTTL)
+		if err != nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+
+		return nil
+	}
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runDeadLetter manage the dead letter queue of a consumer queue and exit, usage:
+//
+//	main dlq list -queue payment_proccess [-limit 50]
+//	main dlq replay -queue payment_proccess [-id id1,id2]
+//	main dlq purge -queue payment_proccess [-id id1,id2]
+//
+// without -id replay and purge apply to every message of the dead letter queue
+func runDeadLetter(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: dlq <list|replay|purge> -queue <queue> [-limit n] [-id id1,id2]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("dlq "+args[0], flag.ContinueOnError)
+	queue := flags.String("queue", "", "consumer queue, e.g. payment_proccess")
+	limit := flags.Int("limit", 50, "maximum message listed, 0 list every message")
+	ids := flags.String("id", "", "comma separated message id to replay or purge")
+
+	err := flags.Parse(args[1:])
+	if err != nil {
+		return 2
+	}
+
+	request := models.DeadLetterRequest{}
+	for _, id := range strings.Split(*ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			request.MessageId = append(request.MessageId, id)
+		}
+	}
+
+	// keep the result on stdout clean
+	logrus.SetOutput(os.Stderr)
+
+	deadLetter := newApp().DeadLetter
+
+	var (
+		code     int
+		response responses.GenericResponse
+	)
+
+	switch args[0] {
+	case "list":
+		code, response = deadLetter.ListDeadLetter(ctx, *queue, *limit)
+	case "replay":
+		code, response = deadLetter.ReplayDeadLetter(ctx, *queue, request)
+	case "purge":
+		code, response = deadLetter.PurgeDeadLetter(ctx, *queue, request)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown dlq command %s\n", args[0])
+		return 2
+	}
+
+	result, err := json.MarshalIndent(response, "", "  ")
+	if err != nil {
+		logrus.Error(err)
+		return 1
+	}
+	fmt.Println(string(result))
+
+	if code != http.StatusOK {
+		return 1
+	}
+
+	return 0
+}
@@ -2,19 +2,13 @@ package main
 
 import (
 	"context"
-	"net/http"
+	"fmt"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
 	"github.com/galihfebrizki/dbo-api/config"
 	"github.com/galihfebrizki/dbo-api/helper"
 	"github.com/galihfebrizki/dbo-api/utils/log"
 	utils "github.com/galihfebrizki/dbo-api/utils/snowflake"
-
-	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 )
 
 func init() {
@@ -22,66 +16,60 @@ func init() {
 	utils.InitSnowflakeUser()
 	utils.InitSnowflakeOrder()
 	utils.InitSnowflakeOrderItem()
+	utils.InitSnowflakeExport()
+	utils.InitSnowflakeInvoice()
+	utils.InitSnowflakeAddress()
+	utils.InitSnowflakeShipment()
+	utils.InitSnowflakePayment()
+	utils.InitSnowflakeOutbox()
 	log.InitLog(config.Get().Env, config.Get().LogLevel)
 }
 
 func main() {
 	ctx := helper.SetRequestIDToContext(context.Background(), helper.GenerateRandomString(32))
 
-	cfg := config.Get()
-
-	signal.Notify(helper.ExitAMQP, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
-
-	defer close(helper.ExitAMQP)
-	defer close(helper.ExitHTTP)
-	defer close(helper.ExitConsumer)
-
-	// init consumer
-	consumer := InitializedConsumer(
-		config.BuildMasterDBParam(),
-		config.BuildSlaveDBParam(),
-		config.BuildRedisParam(),
-		config.BuildRabbitMQParam(),
-	)
-
-	// init server
-	gin := InitializedServer(
-		config.BuildMasterDBParam(),
-		config.BuildSlaveDBParam(),
-		config.BuildRedisParam(),
-		config.BuildRabbitMQParam(),
-	)
-
-	startConsumer(ctx, consumer, cfg)
-
-	startServer(ctx, gin, cfg)
-}
-
-func startServer(ctx context.Context, e *gin.Engine, cfg config.ConfigStructure) {
-	srv := &http.Server{
-		Addr:    cfg.Port,
-		Handler: e,
+	// without command the server, the consumer and the outbox relay run in one process
+	if len(os.Args) < 2 {
+		os.Exit(runProcess(ctx, process{serve: true, consume: true, relay: true}))
 	}
 
-	go func() {
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logrus.Fatal("shutting down the server")
-		}
-	}()
-
-	// Wait for exit signal
-	<-helper.ExitHTTP
-	logrus.WithField(helper.GetRequestIDContext(ctx)).Infoln("Wait for http process done")
-
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.ServerTimeout*int(time.Second)))
-	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		logrus.Fatal(err)
+	args := os.Args[2:]
+
+	switch os.Args[1] {
+	case "serve":
+		os.Exit(runProcess(ctx, process{serve: true}))
+	case "consume":
+		os.Exit(runProcess(ctx, process{consume: true, queues: args}))
+	case "outbox-relay":
+		os.Exit(runProcess(ctx, process{relay: true}))
+	case "migrate":
+		os.Exit(runMigrate(ctx, args))
+	case "seed":
+		os.Exit(runSeed(ctx))
+	case "create-admin":
+		os.Exit(runCreateAdmin(ctx, args))
+	case "reconcile":
+		os.Exit(runReconcile(ctx, args))
+	case "dlq":
+		os.Exit(runDeadLetter(ctx, args))
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
 	}
-
-	logrus.WithField(helper.GetRequestIDContext(ctx)).Infoln("http already exited")
 }
 
-func startConsumer(ctx context.Context, amqp *AmqpController, cfg config.ConfigStructure) {
-	go amqp.StartConsumer(ctx, cfg)
-}
+const usage = `usage: main [command]
+
+without command the server, the consumer and the outbox relay run in one process
+
+command:
+  serve                 run the http server
+  consume [queue...]    run the consumer of the queue, every queue when none is given
+  outbox-relay          publish the outbox message
+  migrate [up|down|status]
+                        apply, revert or list the database migration, without command apply every pending one
+  seed                  insert the default admin
+  create-admin          create a super user, see create-admin -h
+  reconcile             reconcile order payment with the provider, see reconcile -h
+  dlq                   manage the dead letter queue, see dlq
+`
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/internal/database"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/migrate"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runMigrate migrate the master database and exit, only the database is connected, usage:
+//
+//	main migrate up [-steps n]
+//	main migrate down [-steps n]
+//	main migrate status
+//
+// without command every pending migration is applied, down revert one migration by default
+func runMigrate(ctx context.Context, args []string) int {
+	command := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	steps := flags.Int("steps", 0, "number of migration applied or reverted, 0 apply every pending migration and revert one")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	master := gorm.NewGormMasterConnectionPostgres(config.BuildMasterDBParam())
+	defer master.Close()
+
+	sqlDB, err := master.DB().DB()
+	if err != nil {
+		logrus.Error(err)
+		return 1
+	}
+
+	migrator, err := migrate.NewMigrator(sqlDB, database.Migrations())
+	if err != nil {
+		logrus.Error(err)
+		return 1
+	}
+
+	switch command {
+	case "up":
+		done, err := migrator.Up(ctx, *steps)
+		for _, migration := range done {
+			logrus.Infof("migration %d_%s applied", migration.Version, migration.Name)
+		}
+		if err != nil {
+			logrus.Errorf("failed to migrate up : %s", err)
+			return 1
+		}
+		if len(done) == 0 {
+			logrus.Info("no pending migration")
+		}
+	case "down":
+		done, err := migrator.Down(ctx, *steps)
+		for _, migration := range done {
+			logrus.Infof("migration %d_%s reverted", migration.Version, migration.Name)
+		}
+		if err != nil {
+			logrus.Errorf("failed to migrate down : %s", err)
+			return 1
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			logrus.Errorf("failed to read migration status : %s", err)
+			return 1
+		}
+		printStatus(statuses)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown migrate command %s, want up, down or status\n", command)
+		return 2
+	}
+
+	return 0
+}
+
+// runSeed insert the default admin and exit, an existing row is kept
+func runSeed(ctx context.Context) int {
+	master := gorm.NewGormMasterConnectionPostgres(config.BuildMasterDBParam())
+	defer master.Close()
+
+	err := master.WithContext(ctx).DB().Exec(database.Seed).Error
+	if err != nil {
+		logrus.Errorf("failed to apply seed : %s", err)
+		return 1
+	}
+
+	logrus.Info("seed applied")
+
+	return 0
+}
+
+func printStatus(statuses []migrate.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", ""
+		if status.Applied {
+			state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05 -0700")
+		}
+		if status.Modified {
+			state = "modified"
+		}
+		if status.Unknown {
+			state = "unknown"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// StartOutboxRelay publish outbox message until the context is done, a full batch is followed by the next batch without waiting.
+// The batch being published is finished before it return
+func (h *AmqpController) StartOutboxRelay(ctx context.Context, cfg config.ConfigStructure) {
+	ticker := time.NewTicker(time.Duration(cfg.Outbox.RelayInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.WithField(helper.GetRequestIDContext(ctx)).Infoln("outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+
+		for {
+			claimed, err := h.OutboxService.RelayOutbox(lifecycle.WithoutCancel(ctx))
+			if err != nil {
+				log.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			}
+
+			if err != nil || claimed < cfg.Outbox.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/utils/payment"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runReconcile compare order payment with the provider and exit, usage:
+//
+//	main reconcile -from 2024-01-01 -to 2024-01-31 [-settlement report.csv -provider simulator] [-redrive] [-out discrepancy.csv]
+//
+// without settlement report every charge status is queried from its provider
+func runReconcile(ctx context.Context, args []string) int {
+	cfg := config.Get()
+	layout := "2006-01-02"
+	today := time.Now().Format(layout)
+
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	dateFrom := flags.String("from", today, "order created date from, yyyy-mm-dd")
+	dateTo := flags.String("to", today, "order created date to, yyyy-mm-dd")
+	settlementPath := flags.String("settlement", "", "provider settlement report csv with column reference,order_id,amount,status[,settled_at]")
+	provider := flags.String("provider", cfg.Payment.DefaultProvider, "provider of the settlement report")
+	stuckAfter := flags.Int("stuck-after", cfg.Payment.Reconcile.StuckAfter, "minutes without payment progress before ready to pay order is stuck")
+	redrive := flags.Bool("redrive", false, "republish stuck order and apply the provider status to unsettled order and pending refund")
+	outPath := flags.String("out", "", "discrepancy report csv, default to stdout")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return 2
+	}
+
+	for _, date := range []string{*dateFrom, *dateTo} {
+		if _, err := time.Parse(layout, date); err != nil {
+			logrus.Errorf("invalid date %s", date)
+			return 2
+		}
+	}
+
+	var settlement []payment.Settlement
+
+	if *settlementPath != "" {
+		file, err := os.Open(*settlementPath)
+		if err != nil {
+			logrus.Error(err)
+			return 1
+		}
+		defer file.Close()
+
+		settlement, err = payment.ParseSettlement(file)
+		if err != nil {
+			logrus.Error(err)
+			return 1
+		}
+	}
+
+	var out io.Writer = os.Stdout
+
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			logrus.Error(err)
+			return 1
+		}
+		defer file.Close()
+
+		out = file
+	} else {
+		// keep the report on stdout clean
+		logrus.SetOutput(os.Stderr)
+	}
+
+	reconciliation := newApp().Reconciliation
+
+	summary, err := reconciliation.Reconcile(ctx, models.ReconciliationFilter{
+		DateFrom:   *dateFrom,
+		DateTo:     *dateTo,
+		Provider:   *provider,
+		StuckAfter: time.Duration(*stuckAfter) * time.Minute,
+		Redrive:    *redrive,
+	}, settlement, out)
+	if err != nil {
+		logrus.Error(err)
+		return 1
+	}
+
+	logrus.Infof("reconciliation done, checked %d order, %d discrepancy, %d re-driven", summary.Checked, summary.Discrepancy, summary.Redriven)
+
+	return 0
+}
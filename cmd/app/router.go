@@ -16,10 +16,16 @@ func NewRouter(
 	orderController *controllers.OrderController,
 	userController *controllers.UserController,
 	paymentController *controllers.PaymentController,
+	exportController *controllers.ExportController,
+	invoiceController *controllers.InvoiceController,
+	addressController *controllers.AddressController,
+	shipmentController *controllers.ShipmentController,
+	refundController *controllers.RefundController,
+	deadLetterController *controllers.DeadLetterController,
 ) *gin.Engine {
 	r := gin.New()
 
-	r.Use(gin.Recovery())
+	r.Use(middleware.RecoveryMiddleware())
 	r.Use(gzip.Gzip(gzip.DefaultCompression))
 	r.Use(gin.Logger())
 
@@ -32,6 +38,7 @@ func NewRouter(
 	}
 
 	r.Use(requestid.New())
+	r.Use(middleware.ReadAfterWriteMiddleware())
 
 	api := r.Group("/api")
 	api.POST("/login", userController.Login)
@@ -48,7 +55,15 @@ func NewRouter(
 	api.DELETE("/customer", userController.DeleteUser)
 	api.GET("/search-customer", userController.SearchUser)
 
+	api.GET("/address", addressController.GetAddress)
+	api.POST("/address", addressController.CreateAddress)
+	api.PUT("/address", addressController.UpdateAddress)
+	api.DELETE("/address", addressController.DeleteAddress)
+
 	api.GET("/order/:orderId", orderController.GetOrder)
+	api.GET("/order/:orderId/invoice", invoiceController.GetInvoice)
+	api.GET("/order/:orderId/shipment", shipmentController.GetShipment)
+	api.GET("/order/:orderId/refund", refundController.GetRefund)
 	api.GET("/list-order", orderController.GetListOrder)
 	api.POST("/order", orderController.CreateOrder)
 	api.PUT("/order", orderController.UpdateOrder)
@@ -56,9 +71,25 @@ func NewRouter(
 	api.GET("/search-order", orderController.SearchOrder)
 
 	api.POST("/payment-order", paymentController.PaymentOrder)
+	api.POST("/refund-order", refundController.RefundOrder)
+
+	api.POST("/shipment", shipmentController.CreateShipment)
+	api.PUT("/shipment-status", shipmentController.UpdateShipmentStatus)
+
+	api.GET("/export-order", exportController.ExportOrder)
+	api.GET("/export-order/:jobId", exportController.GetExportJob)
+	api.GET("/export-order/:jobId/download", exportController.DownloadExportJob)
+
+	api.GET("/dead-letter/:queue", deadLetterController.ListDeadLetter)
+	api.POST("/dead-letter/:queue/replay", deadLetterController.ReplayDeadLetter)
+	api.POST("/dead-letter/:queue/purge", deadLetterController.PurgeDeadLetter)
 
 	// free access
 	r.GET("/health", healthController.Health)
+	r.GET("/ready", healthController.Ready)
+
+	// called by payment provider, authenticated by signature
+	r.POST("/webhook/payment/:provider", middleware.PaymentWebhookMiddleware(), paymentController.PaymentWebhook)
 
 	return r
 
@@ -4,13 +4,14 @@
 package main
 
 import (
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 
 	"github.com/galihfebrizki/dbo-api/internal/controllers"
 	"github.com/galihfebrizki/dbo-api/internal/repositories"
 	"github.com/galihfebrizki/dbo-api/internal/services"
 	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
+	"github.com/galihfebrizki/dbo-api/utils/payment"
 	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
 	"github.com/galihfebrizki/dbo-api/utils/redis"
 )
@@ -20,6 +21,8 @@ var pkgSet = wire.NewSet(
 	gorm.NewGormSlaveConnectionPostgres,
 	redis.NewRedisConn,
 	rabbitmq.NewRabbitMQConn,
+	payment.NewRegistry,
+	lifecycle.NewManager,
 )
 
 var setHealth = wire.NewSet(
@@ -34,6 +37,12 @@ var setPayment = wire.NewSet(
 	controllers.NewPaymentController,
 )
 
+var setInvoice = wire.NewSet(
+	repositories.NewInvoiceRepository,
+	services.NewInvoiceService,
+	controllers.NewInvoiceController,
+)
+
 var setOrder = wire.NewSet(
 	repositories.NewOrderRepository,
 	services.NewOrderService,
@@ -50,25 +59,74 @@ var setItem = wire.NewSet(
 	repositories.NewItemRepository,
 )
 
-func InitializedServer(masterParam gorm.DBParamMasterConn, slaveParam gorm.DBParamSlaveConn, redisParam redis.RedisParam, mqParam rabbitmq.RabbitMQParam) *gin.Engine {
-	wire.Build(
-		pkgSet,
-		setHealth,
-		setOrder,
-		setUser,
-		setItem,
-		setPayment,
-		NewRouter,
-	)
-	return nil
-}
+var setAddress = wire.NewSet(
+	repositories.NewAddressRepository,
+	services.NewAddressService,
+	controllers.NewAddressController,
+)
+
+var setRefund = wire.NewSet(
+	repositories.NewRefundRepository,
+	services.NewRefundService,
+	controllers.NewRefundController,
+)
+
+var setShipment = wire.NewSet(
+	repositories.NewShipmentRepository,
+	services.NewShipmentService,
+	controllers.NewShipmentController,
+)
+
+var setTax = wire.NewSet(
+	repositories.NewTaxRepository,
+	services.NewTaxService,
+)
+
+var setDeadLetter = wire.NewSet(
+	repositories.NewDeadLetterRepository,
+	services.NewDeadLetterService,
+	controllers.NewDeadLetterController,
+)
+
+var setOutbox = wire.NewSet(
+	repositories.NewOutboxRepository,
+	services.NewOutboxService,
+)
+
+var setReconciliation = wire.NewSet(
+	services.NewReconciliationService,
+)
+
+var setExport = wire.NewSet(
+	repositories.NewExportRepository,
+	services.NewExportService,
+	controllers.NewExportController,
+)
+
+// appSet the whole graph of the app, every command take the part it need from App
+var appSet = wire.NewSet(
+	pkgSet,
+	setHealth,
+	setOrder,
+	setUser,
+	setItem,
+	setTax,
+	setPayment,
+	setExport,
+	setInvoice,
+	setAddress,
+	setShipment,
+	setRefund,
+	setDeadLetter,
+	setOutbox,
+	setReconciliation,
+	NewRouter,
+	NewConsumers,
+	NewAmqpConsumer,
+	wire.Struct(new(App), "*"),
+)
 
-func InitializedConsumer(masterParam gorm.DBParamMasterConn, slaveParam gorm.DBParamSlaveConn, redisParam redis.RedisParam, mqParam rabbitmq.RabbitMQParam) *AmqpController {
-	wire.Build(
-		pkgSet,
-		setPayment,
-		setOrder,
-		NewAmqpConsumer,
-	)
+func InitializedApp(masterParam gorm.DBParamMasterConn, slaveParam gorm.DBParamSlaveConn, redisParam redis.RedisParam, mqParam rabbitmq.RabbitMQParam, lifecycleParam lifecycle.Param) *App {
+	wire.Build(appSet)
 	return nil
 }
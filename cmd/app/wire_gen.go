@@ -11,59 +11,105 @@ import (
 	"github.com/galihfebrizki/dbo-api/internal/repositories"
 	"github.com/galihfebrizki/dbo-api/internal/services"
 	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
+	"github.com/galihfebrizki/dbo-api/utils/payment"
 	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
 	"github.com/galihfebrizki/dbo-api/utils/redis"
-	"github.com/gin-gonic/gin"
 	"github.com/google/wire"
 )
 
 // Injectors from wire.go:
 
-func InitializedServer(masterParam gorm.DBParamMasterConn, slaveParam gorm.DBParamSlaveConn, redisParam redis.RedisParam, mqParam rabbitmq.RabbitMQParam) *gin.Engine {
+func InitializedApp(masterParam gorm.DBParamMasterConn, slaveParam gorm.DBParamSlaveConn, redisParam redis.RedisParam, mqParam rabbitmq.RabbitMQParam, lifecycleParam lifecycle.Param) *App {
 	iGormMaster := gorm.NewGormMasterConnectionPostgres(masterParam)
-	iGormSlave := gorm.NewGormSlaveConnectionPostgres(slaveParam)
 	iredis := redis.NewRedisConn(redisParam)
+	iGormSlave := gorm.NewGormSlaveConnectionPostgres(slaveParam, iGormMaster, iredis)
 	iHealthRepository := repositories.NewHealthRepository(iGormMaster, iGormSlave, iredis)
 	iHealthService := services.NewHealthService(iHealthRepository)
-	healthController := controllers.NewHealthController(iHealthService)
+	manager := lifecycle.NewManager(lifecycleParam)
+	healthController := controllers.NewHealthController(iHealthService, manager)
 	iRabbitMQ := rabbitmq.NewRabbitMQConn(mqParam)
 	iOrderRepository := repositories.NewOrderRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
 	iItemRepository := repositories.NewItemRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
 	iUserRepository := repositories.NewUserRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
 	iUserService := services.NewUserService(iUserRepository, iOrderRepository)
-	iOrderService := services.NewOrderService(iOrderRepository, iItemRepository, iUserService)
+	iTaxRepository := repositories.NewTaxRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
+	iTaxService := services.NewTaxService(iTaxRepository)
+	iAddressRepository := repositories.NewAddressRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
+	iAddressService := services.NewAddressService(iAddressRepository)
+	iOrderService := services.NewOrderService(iOrderRepository, iItemRepository, iUserService, iTaxService, iAddressService)
 	orderController := controllers.NewOrderController(iOrderService, iUserService)
 	userController := controllers.NewUserController(iUserService)
 	iPaymentRepository := repositories.NewPaymentRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
-	iPaymentService := services.NewPaymentService(iPaymentRepository, iOrderRepository)
+	iInvoiceRepository := repositories.NewInvoiceRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
+	iInvoiceService := services.NewInvoiceService(iInvoiceRepository, iOrderRepository, iUserRepository, iUserService)
+	iRegistry := payment.NewRegistry(iredis)
+	iPaymentService := services.NewPaymentService(iPaymentRepository, iOrderRepository, iRegistry)
 	paymentController := controllers.NewPaymentController(iPaymentService)
-	engine := NewRouter(healthController, orderController, userController, paymentController)
-	return engine
-}
-
-func InitializedConsumer(masterParam gorm.DBParamMasterConn, slaveParam gorm.DBParamSlaveConn, redisParam redis.RedisParam, mqParam rabbitmq.RabbitMQParam) *AmqpController {
-	iRabbitMQ := rabbitmq.NewRabbitMQConn(mqParam)
-	iGormMaster := gorm.NewGormMasterConnectionPostgres(masterParam)
-	iGormSlave := gorm.NewGormSlaveConnectionPostgres(slaveParam)
-	iredis := redis.NewRedisConn(redisParam)
-	iPaymentRepository := repositories.NewPaymentRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
-	iOrderRepository := repositories.NewOrderRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
-	iPaymentService := services.NewPaymentService(iPaymentRepository, iOrderRepository)
-	paymentController := controllers.NewPaymentController(iPaymentService)
-	amqpController := NewAmqpConsumer(iRabbitMQ, paymentController)
-	return amqpController
+	iExportRepository := repositories.NewExportRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
+	iExportService := services.NewExportService(iExportRepository)
+	exportController := controllers.NewExportController(iExportService, iUserService)
+	invoiceController := controllers.NewInvoiceController(iInvoiceService)
+	addressController := controllers.NewAddressController(iAddressService)
+	iShipmentRepository := repositories.NewShipmentRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
+	iShipmentService := services.NewShipmentService(iShipmentRepository, iOrderRepository, iUserService)
+	shipmentController := controllers.NewShipmentController(iShipmentService, iUserService)
+	iRefundRepository := repositories.NewRefundRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
+	iRefundService := services.NewRefundService(iRefundRepository, iOrderRepository, iPaymentRepository, iRegistry)
+	refundController := controllers.NewRefundController(iRefundService, iUserService)
+	iDeadLetterRepository := repositories.NewDeadLetterRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
+	iDeadLetterService := services.NewDeadLetterService(iDeadLetterRepository)
+	deadLetterController := controllers.NewDeadLetterController(iDeadLetterService, iUserService)
+	engine := NewRouter(healthController, orderController, userController, paymentController, exportController, invoiceController, addressController, shipmentController, refundController, deadLetterController)
+	v := NewConsumers(orderController, paymentController, exportController, refundController, invoiceController)
+	iOutboxRepository := repositories.NewOutboxRepository(iGormMaster, iGormSlave, iredis, iRabbitMQ)
+	iOutboxService := services.NewOutboxService(iOutboxRepository)
+	amqpController := NewAmqpConsumer(iRabbitMQ, iredis, v, iOutboxService)
+	iReconciliationService := services.NewReconciliationService(iOrderRepository, iPaymentRepository, iPaymentService, iRegistry, iRefundRepository, iRefundService)
+	app := &App{
+		Router:         engine,
+		Consumer:       amqpController,
+		UserService:    iUserService,
+		Reconciliation: iReconciliationService,
+		DeadLetter:     iDeadLetterService,
+		Lifecycle:      manager,
+		Master:         iGormMaster,
+		Slave:          iGormSlave,
+		Redis:          iredis,
+	}
+	return app
 }
 
 // wire.go:
 
-var pkgSet = wire.NewSet(gorm.NewGormMasterConnectionPostgres, gorm.NewGormSlaveConnectionPostgres, redis.NewRedisConn, rabbitmq.NewRabbitMQConn)
+var pkgSet = wire.NewSet(gorm.NewGormMasterConnectionPostgres, gorm.NewGormSlaveConnectionPostgres, redis.NewRedisConn, rabbitmq.NewRabbitMQConn, payment.NewRegistry, lifecycle.NewManager)
 
 var setHealth = wire.NewSet(repositories.NewHealthRepository, services.NewHealthService, controllers.NewHealthController)
 
 var setPayment = wire.NewSet(repositories.NewPaymentRepository, services.NewPaymentService, controllers.NewPaymentController)
 
+var setInvoice = wire.NewSet(repositories.NewInvoiceRepository, services.NewInvoiceService, controllers.NewInvoiceController)
+
 var setOrder = wire.NewSet(repositories.NewOrderRepository, services.NewOrderService, controllers.NewOrderController)
 
 var setUser = wire.NewSet(repositories.NewUserRepository, services.NewUserService, controllers.NewUserController)
 
 var setItem = wire.NewSet(repositories.NewItemRepository)
+
+var setAddress = wire.NewSet(repositories.NewAddressRepository, services.NewAddressService, controllers.NewAddressController)
+
+var setRefund = wire.NewSet(repositories.NewRefundRepository, services.NewRefundService, controllers.NewRefundController)
+
+var setShipment = wire.NewSet(repositories.NewShipmentRepository, services.NewShipmentService, controllers.NewShipmentController)
+
+var setTax = wire.NewSet(repositories.NewTaxRepository, services.NewTaxService)
+
+var setDeadLetter = wire.NewSet(repositories.NewDeadLetterRepository, services.NewDeadLetterService, controllers.NewDeadLetterController)
+
+var setOutbox = wire.NewSet(repositories.NewOutboxRepository, services.NewOutboxService)
+
+var setReconciliation = wire.NewSet(services.NewReconciliationService)
+
+var setExport = wire.NewSet(repositories.NewExportRepository, services.NewExportService, controllers.NewExportController)
+
+var appSet = wire.NewSet(pkgSet, setHealth, setOrder, setUser, setItem, setTax, setPayment, setExport, setInvoice, setAddress, setShipment, setRefund, setDeadLetter, setOutbox, setReconciliation, NewRouter, NewConsumers, NewAmqpConsumer, wire.Struct(new(App), "*"))
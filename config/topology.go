@@ -0,0 +1,41 @@
+package config
+
+import (
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+)
+
+// BuildTopology exchange, queue and subscription of the consumer, applied by every instance on connect
+func BuildTopology() rabbitmq.Topology {
+	topology := rabbitmq.Topology{
+		Exchanges: []rabbitmq.Exchange{
+			{Name: helper.EventExchange, Kind: rabbitmq.ExchangeTopic, Durable: true},
+		},
+		Queues: []rabbitmq.Queue{
+			{Name: helper.PaymentProccess, Durable: true},
+			{Name: helper.OrderExport, Durable: true},
+			{Name: helper.RefundProccess, Durable: true},
+			{Name: helper.InvoiceGenerate, Durable: true},
+			{Name: helper.OrderActivity, Durable: true},
+		},
+	}
+
+	subscriptions := [][]rabbitmq.Binding{
+		rabbitmq.Subscribe(helper.EventExchange, helper.PaymentProccess, helper.EventPaymentRequested),
+		rabbitmq.Subscribe(helper.EventExchange, helper.OrderExport, helper.EventExportRequested),
+		rabbitmq.Subscribe(helper.EventExchange, helper.RefundProccess, helper.EventRefundRequested),
+		rabbitmq.Subscribe(helper.EventExchange, helper.InvoiceGenerate, helper.EventOrderPaid),
+		// every order event, whatever its name
+		rabbitmq.Subscribe(helper.EventExchange, helper.OrderActivity, helper.EventOrderAll),
+
+		// outbox message written before the event exchange use the queue name as topic
+		rabbitmq.Subscribe(helper.EventExchange, helper.PaymentProccess, helper.PaymentProccess),
+		rabbitmq.Subscribe(helper.EventExchange, helper.OrderExport, helper.OrderExport),
+		rabbitmq.Subscribe(helper.EventExchange, helper.RefundProccess, helper.RefundProccess),
+	}
+	for _, bindings := range subscriptions {
+		topology.Bindings = append(topology.Bindings, bindings...)
+	}
+
+	return topology
+}
@@ -3,18 +3,120 @@ package helper
 const (
 	RequestIDContextKey = "request_id"
 	XRequestIDHeaderKey = "X-Request-Id"
+	// W3C trace context of the request, continued by the message published while handling it
+	TraceParentContextKey = "traceparent"
+	TraceParentHeaderKey  = "traceparent"
+	// authenticated user of the request, a write of the user is read from the master by the next request
+	UserIDContextKey = "user_id"
 )
 
 // topic consumer
 const (
 	PaymentProccess = "payment_proccess"
+	OrderExport     = "order_export"
+	RefundProccess  = "refund_proccess"
+	InvoiceGenerate = "invoice_generate"
+	OrderActivity   = "order_activity"
+)
+
+// event exchange, producer publish an event by routing key and consumer queue subscribe by pattern
+const EventExchange = "dbo.events"
+
+// event routing key, <aggregate>.<event>
+const (
+	EventOrderCreated     = "order.created"
+	EventPaymentRequested = "order.payment_requested"
+	EventOrderPaid        = "order.paid"
+	EventRefundRequested  = "refund.requested"
+	EventExportRequested  = "export.requested"
+	// EventOrderAll pattern of every order event
+	EventOrderAll = "order.*"
+)
+
+// processed message state, key: processed_message:<queue>:<message id>
+const (
+	ProcessedMessageKey = "processed_message:%s:%s"
+	MessageProcessing   = "processing"
+	MessageProcessed    = "processed"
 )
 
 // status order
 const (
-	StatusCreate     = 1
-	StatusReadyToPay = 2
-	StatusPaid       = 3
-	StatusSuccess    = 4
-	StatusFailed     = 10
+	StatusCreate            = 1
+	StatusReadyToPay        = 2
+	StatusPaid              = 3
+	StatusSuccess           = 4
+	StatusRefunded          = 5
+	StatusPartiallyRefunded = 6
+	StatusFailed            = 10
+)
+
+// status user
+const (
+	UserActive = 1
+	UserBanned = 2
+)
+
+// level user, a level above zero is super user
+const (
+	UserLevelCustomer = 0
+	UserLevelAdmin    = 1
+)
+
+// status refund
+const (
+	RefundPending = 1
+	RefundSuccess = 3
+	RefundFailed  = 10
+)
+
+// status shipment
+const (
+	ShipmentCreated   = 1
+	ShipmentPickedUp  = 2
+	ShipmentInTransit = 3
+	ShipmentDelivered = 4
+	ShipmentFailed    = 10
+)
+
+// invoice number: INV/<yyyymm>/<sequence of the month>
+const (
+	InvoiceNumberFormat = "INV/%s/%06d"
+)
+
+// status outbox message
+const (
+	OutboxPending   = 1
+	OutboxPublished = 3
+	OutboxFailed    = 10
+)
+
+// status export job
+const (
+	ExportJobPending    = 1
+	ExportJobProcessing = 2
+	ExportJobDone       = 3
+	ExportJobFailed     = 10
+)
+
+// reconciliation discrepancy
+const (
+	DiscrepancyStuckOrder        = "stuck_order"
+	DiscrepancyPendingPayment    = "pending_payment"
+	DiscrepancyNotSettled        = "paid_not_settled"
+	DiscrepancySettledNotPaid    = "settled_not_paid"
+	DiscrepancyFailedNotRecorded = "failed_not_recorded"
+	DiscrepancySettledFailed     = "settled_failed_order"
+	DiscrepancyAmountMismatch    = "amount_mismatch"
+	DiscrepancyUnknownSettlement = "unknown_settlement"
+	DiscrepancyPendingRefund     = "pending_refund"
+)
+
+// reconciliation action
+const (
+	ReconcileRepublished  = "republished"
+	ReconcileMarkedPaid   = "marked_paid"
+	ReconcileMarkedFailed = "marked_failed"
+	ReconcileRefunded     = "refunded"
+	ReconcileRefundFailed = "refund_failed"
 )
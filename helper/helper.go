@@ -11,6 +11,10 @@ import (
 
 func GetGinContext(c *gin.Context) context.Context {
 	ctx := context.WithValue(c.Request.Context(), RequestIDContextKey, c.GetHeader(XRequestIDHeaderKey))
+	ctx = context.WithValue(ctx, TraceParentContextKey, c.GetHeader(TraceParentHeaderKey))
+	if userId := c.GetString("UserId"); userId != "" {
+		ctx = context.WithValue(ctx, UserIDContextKey, userId)
+	}
 	return ctx
 }
 
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AddressController struct {
+	AddressService services.IAddressService
+}
+
+func NewAddressController(service services.IAddressService) *AddressController {
+	return &AddressController{
+		AddressService: service,
+	}
+}
+
+func (h *AddressController) GetAddress(c *gin.Context) {
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	c.JSON(h.AddressService.GetAddress(ctx, userId.(string)))
+}
+
+func (h *AddressController) CreateAddress(c *gin.Context) {
+	var request models.Address
+
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	// Parse the JSON request body
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	c.JSON(h.AddressService.CreateAddress(ctx, userId.(string), request))
+}
+
+func (h *AddressController) UpdateAddress(c *gin.Context) {
+	var request models.Address
+
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	// Parse the JSON request body
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	if request.Id == "" {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	c.JSON(h.AddressService.UpdateAddress(ctx, userId.(string), request))
+}
+
+func (h *AddressController) DeleteAddress(c *gin.Context) {
+
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	addressIdParam := c.Query("id")
+	if addressIdParam == "" {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	c.JSON(h.AddressService.DeleteAddress(ctx, userId.(string), addressIdParam))
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DeadLetterController struct {
+	DeadLetterService services.IDeadLetterService
+	UserService       services.IUserService
+}
+
+func NewDeadLetterController(service services.IDeadLetterService, userService services.IUserService) *DeadLetterController {
+	return &DeadLetterController{
+		DeadLetterService: service,
+		UserService:       userService,
+	}
+}
+
+func (h *DeadLetterController) ListDeadLetter(c *gin.Context) {
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if isSuperUser {
+		c.JSON(h.DeadLetterService.ListDeadLetter(ctx, c.Param("queue"), limit))
+	} else {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+	}
+}
+
+func (h *DeadLetterController) ReplayDeadLetter(c *gin.Context) {
+	var request models.DeadLetterRequest
+
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	// Parse the JSON request body, empty body replay every message
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+			return
+		}
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if isSuperUser {
+		c.JSON(h.DeadLetterService.ReplayDeadLetter(ctx, c.Param("queue"), request))
+	} else {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+	}
+}
+
+func (h *DeadLetterController) PurgeDeadLetter(c *gin.Context) {
+	var request models.DeadLetterRequest
+
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	// Parse the JSON request body, empty body purge every message
+	if c.Request.ContentLength != 0 {
+		if err := c.BindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+			return
+		}
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if isSuperUser {
+		c.JSON(h.DeadLetterService.PurgeDeadLetter(ctx, c.Param("queue"), request))
+	} else {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+	}
+}
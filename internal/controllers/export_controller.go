@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/internal/services"
+	"github.com/galihfebrizki/dbo-api/utils/export"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ExportController struct {
+	ExportService services.IExportService
+	UserService   services.IUserService
+}
+
+func NewExportController(service services.IExportService, userService services.IUserService) *ExportController {
+	return &ExportController{
+		ExportService: service,
+		UserService:   userService,
+	}
+}
+
+func (h *ExportController) ConsumerOrderExport(ctx context.Context, job models.ExportJob) error {
+	err := h.ExportService.ExportOrderJobReceived(ctx, job)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (h *ExportController) ExportOrder(c *gin.Context) {
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	filter := models.ExportOrderFilter{
+		DateFrom:      c.Query("date_from"),
+		DateTo:        c.Query("date_to"),
+		PaymentMethod: c.Query("payment_method"),
+		UserId:        c.Query("user_id"),
+		Format:        c.DefaultQuery("format", export.FormatCSV),
+	}
+
+	if !export.IsValidFormat(filter.Format) {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	layout := "2006-01-02"
+
+	dateFrom, err := time.Parse(layout, filter.DateFrom)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	dateTo, err := time.Parse(layout, filter.DateTo)
+	if err != nil || dateTo.Before(dateFrom) {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	if c.Query("status") != "" {
+		filter.Status, err = strconv.Atoi(c.Query("status"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+			return
+		}
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if !isSuperUser {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+		return
+	}
+
+	if c.Query("async") == "true" || h.ExportService.IsAsyncExport(filter) {
+		c.JSON(h.ExportService.CreateExportJob(ctx, userId.(string), filter))
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(filter.Format))
+	c.Header("Content-Disposition", `attachment; filename="orders_`+filter.DateFrom+"_"+filter.DateTo+"."+filter.Format+`"`)
+	c.Status(http.StatusOK)
+
+	err = h.ExportService.ExportOrder(ctx, filter, c.Writer)
+	if err != nil {
+		// headers and part of the file are already sent, a status can not be written anymore. Abort the
+		// connection without the end of the body so the client see a truncated download instead of a complete file
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		panic(http.ErrAbortHandler)
+	}
+}
+
+func (h *ExportController) GetExportJob(c *gin.Context) {
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	jobId := c.Param("jobId")
+	if jobId == "" {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if isSuperUser {
+		c.JSON(h.ExportService.GetExportJob(ctx, jobId))
+	} else {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+	}
+}
+
+func (h *ExportController) DownloadExportJob(c *gin.Context) {
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	jobId := c.Param("jobId")
+	if jobId == "" {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if !isSuperUser {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+		return
+	}
+
+	filePath, fileName, err := h.ExportService.GetExportFile(ctx, jobId)
+	if err != nil {
+		c.JSON(http.StatusOK, *responses.NewGenericResponse(1015, nil))
+		return
+	}
+
+	c.FileAttachment(filePath, fileName)
+}
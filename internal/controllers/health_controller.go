@@ -4,18 +4,22 @@ import (
 	"net/http"
 
 	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
 	"github.com/galihfebrizki/dbo-api/internal/services"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
 
 	"github.com/gin-gonic/gin"
 )
 
 type HealthController struct {
 	HealthService services.IHealthService
+	Lifecycle     *lifecycle.Manager
 }
 
-func NewHealthController(service services.IHealthService) *HealthController {
+func NewHealthController(service services.IHealthService, manager *lifecycle.Manager) *HealthController {
 	return &HealthController{
 		HealthService: service,
+		Lifecycle:     manager,
 	}
 }
 
@@ -23,3 +27,13 @@ func (h *HealthController) Health(c *gin.Context) {
 	ctx := helper.GetGinContext(c)
 	c.JSON(http.StatusOK, h.HealthService.HealthCheck(ctx))
 }
+
+// Ready readiness probe, not ready once the process is stopping so the load balancer stop sending request
+func (h *HealthController) Ready(c *gin.Context) {
+	if !h.Lifecycle.Ready() {
+		c.JSON(http.StatusServiceUnavailable, models.Readiness{Ready: false})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Readiness{Ready: true})
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type InvoiceController struct {
+	InvoiceService services.IInvoiceService
+}
+
+func NewInvoiceController(service services.IInvoiceService) *InvoiceController {
+	return &InvoiceController{
+		InvoiceService: service,
+	}
+}
+
+// ConsumerOrderPaid generate the invoice of the paid order from order.paid
+func (h *InvoiceController) ConsumerOrderPaid(ctx context.Context, event models.OrderPaidEvent) error {
+	_, err := h.InvoiceService.GenerateInvoice(ctx, event.OrderId)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (h *InvoiceController) GetInvoice(c *gin.Context) {
+	ctx := helper.GetGinContext(c)
+
+	orderId := c.Param("orderId")
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	format := c.DefaultQuery("format", services.InvoiceFormatPDF)
+	if format != services.InvoiceFormatPDF && format != services.InvoiceFormatHTML {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	status, response := h.InvoiceService.GetInvoice(ctx, orderId, userId.(string))
+	if !response.Success {
+		c.JSON(status, response)
+		return
+	}
+
+	invoice := response.Data.(models.Invoice)
+
+	if format == services.InvoiceFormatPDF {
+		c.Header("Content-Type", "application/pdf")
+		c.Header("Content-Disposition", `inline; filename="invoice_`+invoice.Id+`.pdf"`)
+	} else {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+	}
+	c.Status(http.StatusOK)
+
+	err := h.InvoiceService.RenderInvoice(ctx, invoice, format, c.Writer)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		c.Abort()
+	}
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,8 +10,10 @@ import (
 	"github.com/galihfebrizki/dbo-api/internal/models"
 	"github.com/galihfebrizki/dbo-api/internal/responses"
 	"github.com/galihfebrizki/dbo-api/internal/services"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type OrderController struct {
@@ -176,3 +179,16 @@ func (h *OrderController) SearchOrder(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
 	}
 }
+
+// ConsumerOrderActivity trace every order.* event of the order, whatever its payload
+func (h *OrderController) ConsumerOrderActivity(ctx context.Context, event models.OrderActivityEvent) error {
+	orderId := event.OrderId
+	if orderId == "" {
+		orderId = event.Id
+	}
+
+	envelope, _ := rabbitmq.EnvelopeFromContext(ctx)
+	logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("order %s %s by %s with correlation id %s", orderId, envelope.EventType, envelope.Producer, envelope.CorrelationId)
+
+	return nil
+}
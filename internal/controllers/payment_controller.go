@@ -11,7 +11,6 @@ import (
 	"github.com/galihfebrizki/dbo-api/internal/services"
 
 	"github.com/gin-gonic/gin"
-	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/sirupsen/logrus"
 )
 
@@ -25,25 +24,14 @@ func NewPaymentController(service services.IPaymentService) *PaymentController {
 	}
 }
 
-func (h *PaymentController) ConsumerPaymentProccess(ctx context.Context, message amqp.Delivery) bool {
-	var orderData models.Order
-
-	ctx = helper.SetRequestIDToContext(ctx, message.MessageId)
-	logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("Message inbound : %s", message.Body)
-
-	err := json.Unmarshal([]byte(message.Body), &orderData)
-	if err != nil {
-		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err.Error())
-		return false
-	}
-
-	err = h.PaymentService.PaymentProccessReceived(ctx, orderData)
+func (h *PaymentController) ConsumerPaymentProccess(ctx context.Context, orderData models.Order) error {
+	err := h.PaymentService.PaymentProccessReceived(ctx, orderData)
 	if err != nil {
 		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err.Error())
-		return false
+		return err
 	}
 
-	return true
+	return nil
 }
 
 func (h *PaymentController) PaymentOrder(c *gin.Context) {
@@ -59,3 +47,24 @@ func (h *PaymentController) PaymentOrder(c *gin.Context) {
 
 	c.JSON(h.PaymentService.PaymentProccessSend(ctx, request.OrderId))
 }
+
+func (h *PaymentController) PaymentWebhook(c *gin.Context) {
+	var request models.PaymentWebhook
+
+	ctx := helper.GetGinContext(c)
+
+	// raw body is stored with the event for audit
+	body, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	err = json.Unmarshal(body, &request)
+	if err != nil || request.EventId == "" || request.OrderId == "" || request.Status == "" {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	c.JSON(h.PaymentService.PaymentWebhookReceived(ctx, c.Param("provider"), request, body))
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type RefundController struct {
+	RefundService services.IRefundService
+	UserService   services.IUserService
+}
+
+func NewRefundController(service services.IRefundService, userService services.IUserService) *RefundController {
+	return &RefundController{
+		RefundService: service,
+		UserService:   userService,
+	}
+}
+
+func (h *RefundController) ConsumerRefundProccess(ctx context.Context, refundData models.Refund) error {
+	err := h.RefundService.RefundProccessReceived(ctx, refundData)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (h *RefundController) GetRefund(c *gin.Context) {
+	ctx := helper.GetGinContext(c)
+
+	orderId := c.Param("orderId")
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if isSuperUser {
+		c.JSON(h.RefundService.GetRefund(ctx, orderId))
+	} else {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+	}
+}
+
+func (h *RefundController) RefundOrder(c *gin.Context) {
+	var request models.RefundOrder
+
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	// Parse the JSON request body
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if isSuperUser {
+		c.JSON(h.RefundService.RefundProccessSend(ctx, request, userId.(string)))
+	} else {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+	}
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ShipmentController struct {
+	ShipmentService services.IShipmentService
+	UserService     services.IUserService
+}
+
+func NewShipmentController(service services.IShipmentService, userService services.IUserService) *ShipmentController {
+	return &ShipmentController{
+		ShipmentService: service,
+		UserService:     userService,
+	}
+}
+
+func (h *ShipmentController) GetShipment(c *gin.Context) {
+	ctx := helper.GetGinContext(c)
+
+	orderId := c.Param("orderId")
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	c.JSON(h.ShipmentService.GetShipment(ctx, orderId, userId.(string)))
+}
+
+func (h *ShipmentController) CreateShipment(c *gin.Context) {
+	var request models.CreateShipment
+
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	// Parse the JSON request body
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if isSuperUser {
+		c.JSON(h.ShipmentService.CreateShipment(ctx, request))
+	} else {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+	}
+}
+
+func (h *ShipmentController) UpdateShipmentStatus(c *gin.Context) {
+	var request models.UpdateShipmentStatus
+
+	ctx := helper.GetGinContext(c)
+
+	userId, ok := c.Get("UserId")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1001, nil))
+		return
+	}
+
+	// Parse the JSON request body
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+		return
+	}
+
+	isSuperUser := h.UserService.IsSuperUser(ctx, userId.(string))
+	if isSuperUser {
+		c.JSON(h.ShipmentService.UpdateShipmentStatus(ctx, request))
+	} else {
+		c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil))
+	}
+}
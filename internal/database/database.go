@@ -0,0 +1,25 @@
+package database
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// Seed the default admin for development, a row that already exist is kept
+//
+//go:embed seed.sql
+var Seed string
+
+// Migrations versioned schema migration, <version>_<name>.up.sql and <version>_<name>.down.sql.
+// An applied migration must not be edited, add a new version instead
+func Migrations() fs.FS {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
@@ -0,0 +1,26 @@
+-- Revert the baseline, every table of the app and its data is dropped
+
+DROP TABLE IF EXISTS public.users;
+DROP TABLE IF EXISTS public.user_status;
+DROP TABLE IF EXISTS public.user_sessions;
+DROP TABLE IF EXISTS public.tax_classes;
+DROP TABLE IF EXISTS public.shipments;
+DROP TABLE IF EXISTS public.shipment_status;
+DROP TABLE IF EXISTS public.shipment_logs;
+DROP TABLE IF EXISTS public.refunds;
+DROP TABLE IF EXISTS public.refund_items;
+DROP TABLE IF EXISTS public.quantity_type;
+DROP TABLE IF EXISTS public.payment_webhook_events;
+DROP TABLE IF EXISTS public.payment_attempts;
+DROP TABLE IF EXISTS public.outbox_messages;
+DROP TABLE IF EXISTS public.orders;
+DROP TABLE IF EXISTS public.order_status;
+DROP TABLE IF EXISTS public.order_logs;
+DROP TABLE IF EXISTS public.order_items;
+DROP TABLE IF EXISTS public.invoices;
+DROP TABLE IF EXISTS public.invoice_sequences;
+DROP TABLE IF EXISTS public.invoice_items;
+DROP TABLE IF EXISTS public.export_jobs;
+DROP TABLE IF EXISTS public.items;
+DROP TABLE IF EXISTS public.customer_data;
+DROP TABLE IF EXISTS public.addresses;
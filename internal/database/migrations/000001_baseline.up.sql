@@ -0,0 +1,576 @@
+-- DROP SCHEMA public;
+
+COMMENT ON SCHEMA public IS 'standard public schema';
+-- public.addresses definition
+
+-- Drop table
+
+-- DROP TABLE public.addresses;
+
+CREATE TABLE IF NOT EXISTS public.addresses (
+	id varchar(50) NOT NULL,
+	user_id varchar(50) NOT NULL,
+	"label" varchar(50) NOT NULL,
+	recipient_name varchar(100) NOT NULL,
+	phone_number varchar(20) NOT NULL,
+	address varchar(200) NOT NULL,
+	district_address varchar(30) NULL,
+	city_address varchar(30) NULL,
+	province_address varchar(30) NULL,
+	postal_code int4 NULL,
+	latitude_address varchar(30) NULL,
+	longitude_address varchar(30) NULL,
+	is_default bool NOT NULL DEFAULT false,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT addresses_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS addresses_user_id_idx ON public.addresses USING btree (user_id);
+
+
+-- public.customer_data definition
+
+-- Drop table
+
+-- DROP TABLE public.customer_data;
+
+CREATE TABLE IF NOT EXISTS public.customer_data (
+	user_id varchar(50) NOT NULL,
+	dob date NULL,
+	phone_number varchar(20) NULL,
+	gender bpchar(1) NULL,
+	marital_status varchar(10) NULL,
+	address varchar(200) NULL,
+	district_address varchar(30) NULL,
+	city_address varchar(30) NULL,
+	province_address varchar(30) NULL,
+	postal_code int4 NULL,
+	latitude_address varchar(30) NULL,
+	longitude_address varchar(30) NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL
+);
+
+
+-- public.items definition
+
+-- Drop table
+
+-- DROP TABLE public.items;
+
+CREATE TABLE IF NOT EXISTS public.items (
+	id varchar(50) NOT NULL,
+	item_name varchar(100) NOT NULL,
+	sku varchar(30) NOT NULL,
+	price int8 NOT NULL DEFAULT 0,
+	quantity_type int4 NOT NULL,
+	stock int4 NOT NULL DEFAULT 0,
+	tax_class varchar(20) NOT NULL DEFAULT 'PPN11',
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT items_pkey PRIMARY KEY (id)
+);
+
+
+-- public.export_jobs definition
+
+-- Drop table
+
+-- DROP TABLE public.export_jobs;
+
+CREATE TABLE IF NOT EXISTS public.export_jobs (
+	id varchar(50) NOT NULL,
+	user_id varchar(50) NOT NULL,
+	format varchar(10) NOT NULL,
+	filter jsonb NULL,
+	status int4 NOT NULL DEFAULT 1,
+	file_name varchar(100) NULL,
+	total_rows int8 NOT NULL DEFAULT 0,
+	error_message text NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	finished_at timestamptz NULL,
+	CONSTRAINT export_jobs_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS export_jobs_user_id_idx ON public.export_jobs USING btree (user_id);
+
+
+-- public.invoice_items definition
+
+-- Drop table
+
+-- DROP TABLE public.invoice_items;
+
+CREATE TABLE IF NOT EXISTS public.invoice_items (
+	invoice_id varchar(50) NOT NULL,
+	order_item_id varchar(50) NOT NULL,
+	item_id varchar(50) NOT NULL,
+	item_name varchar(100) NOT NULL,
+	sku varchar(30) NOT NULL,
+	quantity int4 NOT NULL,
+	unit_price int8 NOT NULL,
+	discount_amount int8 NOT NULL DEFAULT 0,
+	tax_class varchar(20) NULL,
+	tax_rate int8 NOT NULL DEFAULT 0,
+	price_inclusive bool NOT NULL DEFAULT false,
+	tax_amount int8 NOT NULL DEFAULT 0,
+	amount int8 NOT NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT invoice_items_pkey PRIMARY KEY (invoice_id, order_item_id)
+);
+
+
+-- public.invoice_sequences definition
+
+-- Drop table
+
+-- DROP TABLE public.invoice_sequences;
+
+CREATE TABLE IF NOT EXISTS public.invoice_sequences (
+	"period" bpchar(6) NOT NULL,
+	last_number int4 NOT NULL DEFAULT 0,
+	updated_at timestamptz NULL,
+	CONSTRAINT invoice_sequences_pkey PRIMARY KEY (period)
+);
+
+
+-- public.invoices definition
+
+-- Drop table
+
+-- DROP TABLE public.invoices;
+
+CREATE TABLE IF NOT EXISTS public.invoices (
+	id varchar(50) NOT NULL,
+	invoice_number varchar(30) NOT NULL,
+	order_id varchar(50) NOT NULL,
+	user_id varchar(50) NOT NULL,
+	customer_name varchar(100) NULL,
+	"period" bpchar(6) NOT NULL,
+	"sequence" int4 NOT NULL,
+	subtotal_amount int8 NOT NULL,
+	discount_amount int8 NOT NULL DEFAULT 0,
+	tax_amount int8 NOT NULL DEFAULT 0,
+	total_amount int8 NOT NULL,
+	payment_method varchar(30) NOT NULL,
+	payment_acquirement_id varchar(50) NULL,
+	payment_date timestamptz NULL,
+	issued_at timestamptz NOT NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT invoices_pkey PRIMARY KEY (id),
+	CONSTRAINT invoices_invoice_number_key UNIQUE (invoice_number),
+	CONSTRAINT invoices_order_id_key UNIQUE (order_id),
+	CONSTRAINT invoices_period_sequence_key UNIQUE (period, sequence)
+);
+CREATE INDEX IF NOT EXISTS invoices_user_id_idx ON public.invoices USING btree (user_id);
+
+
+-- public.order_items definition
+
+-- Drop table
+
+-- DROP TABLE public.order_items;
+
+CREATE TABLE IF NOT EXISTS public.order_items (
+	id varchar(50) NOT NULL,
+	order_id varchar(50) NOT NULL,
+	item_id varchar(50) NOT NULL,
+	quantity int4 NOT NULL,
+	item_price int8 NOT NULL,
+	discount_amount int8 NOT NULL,
+	tax_class varchar(20) NULL,
+	tax_rate int8 NOT NULL DEFAULT 0,
+	price_inclusive bool NOT NULL DEFAULT false,
+	tax_amount int8 NOT NULL DEFAULT 0,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT order_items_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS order_items_order_id_idx ON public.order_items USING btree (order_id);
+
+
+-- public.order_logs definition
+
+-- Drop table
+
+-- DROP TABLE public.order_logs;
+
+CREATE TABLE IF NOT EXISTS public.order_logs (
+	order_id varchar(50) NOT NULL,
+	order_status int4 NOT NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL
+);
+
+
+-- public.order_status definition
+
+-- Drop table
+
+-- DROP TABLE public.order_status;
+
+CREATE TABLE IF NOT EXISTS public.order_status (
+	id int4 NOT NULL,
+	"name" varchar(50) NOT NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT order_status_pkey PRIMARY KEY (id)
+);
+
+
+-- public.orders definition
+
+-- Drop table
+
+-- DROP TABLE public.orders;
+
+CREATE TABLE IF NOT EXISTS public.orders (
+	id varchar(50) NOT NULL,
+	user_id varchar(50) NOT NULL,
+	status int4 NOT NULL DEFAULT 0,
+	total_amount int8 NOT NULL,
+	total_quantity int4 NOT NULL,
+	total_discount_amount int8 NULL DEFAULT 0,
+	total_tax_amount int8 NOT NULL DEFAULT 0,
+	address_id varchar(50) NULL,
+	shipping_address jsonb NULL,
+	payment_method varchar(30) NOT NULL,
+	payment_acquirement_id varchar(50) NULL,
+	payment_date timestamptz NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT orders_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS orders_created_at_idx ON public.orders USING btree (created_at);
+CREATE INDEX IF NOT EXISTS orders_user_id_idx ON public.orders USING btree (user_id);
+
+
+-- public.outbox_messages definition
+
+-- Drop table
+
+-- DROP TABLE public.outbox_messages;
+
+CREATE TABLE IF NOT EXISTS public.outbox_messages (
+	id varchar(50) NOT NULL,
+	topic varchar(100) NOT NULL,
+	payload jsonb NOT NULL,
+	headers jsonb NULL,
+	status int4 NOT NULL DEFAULT 1,
+	attempts int4 NOT NULL DEFAULT 0,
+	last_error text NULL,
+	next_attempt_at timestamptz NOT NULL,
+	created_at timestamptz NOT NULL,
+	published_at timestamptz NULL,
+	CONSTRAINT outbox_messages_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS outbox_messages_pending_idx ON public.outbox_messages USING btree (next_attempt_at) WHERE status = 1;
+
+
+-- public.payment_attempts definition
+
+-- Drop table
+
+-- DROP TABLE public.payment_attempts;
+
+CREATE TABLE IF NOT EXISTS public.payment_attempts (
+	id varchar(50) NOT NULL,
+	order_id varchar(50) NOT NULL,
+	provider varchar(30) NOT NULL,
+	payment_method varchar(30) NOT NULL,
+	amount int8 NOT NULL,
+	status varchar(20) NOT NULL,
+	reference varchar(100) NULL,
+	failure_reason varchar(200) NULL,
+	paid_at timestamptz NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT payment_attempts_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS payment_attempts_order_id_idx ON public.payment_attempts USING btree (order_id);
+CREATE INDEX IF NOT EXISTS payment_attempts_reference_idx ON public.payment_attempts USING btree (provider, reference);
+
+
+-- public.payment_webhook_events definition
+
+-- Drop table
+
+-- DROP TABLE public.payment_webhook_events;
+
+CREATE TABLE IF NOT EXISTS public.payment_webhook_events (
+	provider varchar(30) NOT NULL,
+	event_id varchar(100) NOT NULL,
+	order_id varchar(50) NOT NULL,
+	status varchar(30) NOT NULL,
+	payload jsonb NULL,
+	received_at timestamptz NOT NULL,
+	processed_at timestamptz NULL,
+	CONSTRAINT payment_webhook_events_pkey PRIMARY KEY (provider, event_id)
+);
+
+
+-- public.quantity_type definition
+
+-- Drop table
+
+-- DROP TABLE public.quantity_type;
+
+CREATE TABLE IF NOT EXISTS public.quantity_type (
+	id int4 NOT NULL,
+	"name" varchar(50) NOT NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT quantity_type_pkey PRIMARY KEY (id)
+);
+
+
+-- public.refund_items definition
+
+-- Drop table
+
+-- DROP TABLE public.refund_items;
+
+CREATE TABLE IF NOT EXISTS public.refund_items (
+	refund_id varchar(50) NOT NULL,
+	order_item_id varchar(50) NOT NULL,
+	item_id varchar(50) NOT NULL,
+	quantity int4 NOT NULL,
+	amount int8 NOT NULL,
+	created_at timestamptz NULL,
+	CONSTRAINT refund_items_pkey PRIMARY KEY (refund_id, order_item_id)
+);
+
+
+-- public.refunds definition
+
+-- Drop table
+
+-- DROP TABLE public.refunds;
+
+CREATE TABLE IF NOT EXISTS public.refunds (
+	id varchar(50) NOT NULL,
+	order_id varchar(50) NOT NULL,
+	payment_attempt_id varchar(50) NOT NULL,
+	amount int8 NOT NULL,
+	reason varchar(200) NULL,
+	status int4 NOT NULL DEFAULT 1,
+	provider_reference varchar(100) NULL,
+	failure_reason varchar(200) NULL,
+	requested_by varchar(50) NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	processed_at timestamptz NULL,
+	CONSTRAINT refunds_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS refunds_order_id_idx ON public.refunds USING btree (order_id);
+
+
+-- public.shipment_logs definition
+
+-- Drop table
+
+-- DROP TABLE public.shipment_logs;
+
+CREATE TABLE IF NOT EXISTS public.shipment_logs (
+	shipment_id varchar(50) NOT NULL,
+	shipment_status int4 NOT NULL,
+	note varchar(200) NULL,
+	created_at timestamptz NULL
+);
+CREATE INDEX IF NOT EXISTS shipment_logs_shipment_id_idx ON public.shipment_logs USING btree (shipment_id);
+
+
+-- public.shipment_status definition
+
+-- Drop table
+
+-- DROP TABLE public.shipment_status;
+
+CREATE TABLE IF NOT EXISTS public.shipment_status (
+	id int4 NOT NULL,
+	"name" varchar(50) NOT NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT shipment_status_pkey PRIMARY KEY (id)
+);
+
+
+-- public.shipments definition
+
+-- Drop table
+
+-- DROP TABLE public.shipments;
+
+CREATE TABLE IF NOT EXISTS public.shipments (
+	id varchar(50) NOT NULL,
+	order_id varchar(50) NOT NULL,
+	courier varchar(30) NOT NULL,
+	service varchar(30) NULL,
+	tracking_number varchar(50) NOT NULL,
+	status int4 NOT NULL DEFAULT 1,
+	shipped_at timestamptz NULL,
+	delivered_at timestamptz NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT shipments_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS shipments_order_id_idx ON public.shipments USING btree (order_id);
+
+
+-- public.tax_classes definition
+
+-- Drop table
+
+-- DROP TABLE public.tax_classes;
+
+CREATE TABLE IF NOT EXISTS public.tax_classes (
+	code varchar(20) NOT NULL,
+	"name" varchar(100) NOT NULL,
+	rate_bps int8 NOT NULL DEFAULT 0,
+	price_inclusive bool NOT NULL DEFAULT false,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT tax_classes_pkey PRIMARY KEY (code)
+);
+
+
+-- public.user_sessions definition
+
+-- Drop table
+
+-- DROP TABLE public.user_sessions;
+
+CREATE TABLE IF NOT EXISTS public.user_sessions (
+	user_id varchar(50) NOT NULL,
+	"token" text NOT NULL,
+	login_time timestamptz NULL,
+	logout_time timestamptz NULL
+);
+CREATE INDEX IF NOT EXISTS user_sessions_logout_time_idx ON public.user_sessions USING btree (logout_time, user_id);
+CREATE INDEX IF NOT EXISTS user_sessions_token_idx ON public.user_sessions USING btree (token);
+CREATE INDEX IF NOT EXISTS user_sessions_user_id_idx ON public.user_sessions USING btree (user_id);
+CREATE INDEX IF NOT EXISTS user_sessions_user_id_token_idx ON public.user_sessions USING btree (user_id, token);
+
+
+-- public.user_status definition
+
+-- Drop table
+
+-- DROP TABLE public.user_status;
+
+CREATE TABLE IF NOT EXISTS public.user_status (
+	id int4 NOT NULL,
+	"name" varchar(50) NULL,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT user_status_pkey PRIMARY KEY (id)
+);
+
+
+-- public.users definition
+
+-- Drop table
+
+-- DROP TABLE public.users;
+
+CREATE TABLE IF NOT EXISTS public.users (
+	id varchar NOT NULL,
+	username varchar(50) NOT NULL,
+	"password" varchar(50) NOT NULL,
+	full_name varchar(100) NOT NULL,
+	status int4 NULL DEFAULT 1,
+	"level" int4 NULL DEFAULT 0,
+	created_at timestamptz NULL,
+	updated_at timestamptz NULL,
+	CONSTRAINT users_pkey PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS users_full_name_idx ON public.users USING btree (full_name);
+CREATE INDEX IF NOT EXISTS users_username_password_idx ON public.users USING btree (username, password);
+
+
+-- public.addresses foreign keys
+
+-- public.customer_data foreign keys
+
+-- public.export_jobs foreign keys
+
+-- public.invoice_items foreign keys
+
+-- public.invoice_sequences foreign keys
+
+-- public.invoices foreign keys
+
+-- public.items foreign keys
+
+-- public.order_items foreign keys
+
+-- public.order_logs foreign keys
+
+-- public.order_status foreign keys
+
+-- public.orders foreign keys
+
+-- public.payment_attempts foreign keys
+
+-- public.payment_webhook_events foreign keys
+
+-- public.quantity_type foreign keys
+
+-- public.refund_items foreign keys
+
+-- public.refunds foreign keys
+
+-- public.shipment_logs foreign keys
+
+-- public.shipment_status foreign keys
+
+-- public.shipments foreign keys
+
+-- public.tax_classes foreign keys
+
+-- public.user_sessions foreign keys
+
+-- public.user_status foreign keys
+
+-- public.users foreign keys
+
+
+
+-- Permissions;
+
+
+GRANT ALL ON SCHEMA public TO pg_database_owner;
+GRANT USAGE ON SCHEMA public TO public;
+
+
+-- Reference data
+
+INSERT INTO public.order_status (id,"name",created_at,updated_at) VALUES
+	 (1,'Create','2023-07-19 10:19:30.783621+00',NULL),
+	 (2,'Ready To Pay','2023-07-19 10:19:30.783621+00',NULL),
+	 (3,'Paid','2023-07-19 10:19:30.783621+00',NULL),
+	 (4,'Success','2023-07-19 10:19:30.783621+00',NULL),
+	 (5,'Refunded','2023-07-19 10:19:30.783621+00',NULL),
+	 (6,'Partially Refunded','2023-07-19 10:19:30.783621+00',NULL),
+	 (10,'Failed','2023-07-19 10:19:30.783621+00',NULL)
+ON CONFLICT DO NOTHING;
+INSERT INTO public.quantity_type (id,"name",created_at,updated_at) VALUES
+	 (1,'PCS','2023-07-19 10:13:51.232978+00',NULL)
+ON CONFLICT DO NOTHING;
+INSERT INTO public.shipment_status (id,"name",created_at,updated_at) VALUES
+	 (1,'Created','2024-01-01 00:00:00+00',NULL),
+	 (2,'Picked Up','2024-01-01 00:00:00+00',NULL),
+	 (3,'In Transit','2024-01-01 00:00:00+00',NULL),
+	 (4,'Delivered','2024-01-01 00:00:00+00',NULL),
+	 (10,'Failed','2024-01-01 00:00:00+00',NULL)
+ON CONFLICT DO NOTHING;
+INSERT INTO public.tax_classes (code,"name",rate_bps,price_inclusive,created_at,updated_at) VALUES
+	 ('PPN11','PPN 11%',1100,false,'2024-01-01 00:00:00+00',NULL),
+	 ('PPN11_INC','PPN 11% (harga termasuk pajak)',1100,true,'2024-01-01 00:00:00+00',NULL),
+	 ('PPN12','PPN 12%',1200,false,'2024-01-01 00:00:00+00',NULL),
+	 ('EXEMPT','Bebas PPN',0,false,'2024-01-01 00:00:00+00',NULL)
+ON CONFLICT DO NOTHING;
+INSERT INTO public.user_status (id,"name",created_at,updated_at) VALUES
+	 (1,'Active','2023-07-19 10:18:57.789588+00',NULL),
+	 (2,'Banned','2023-07-19 10:18:57.798501+00',NULL)
+ON CONFLICT DO NOTHING;
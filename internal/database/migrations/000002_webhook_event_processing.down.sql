@@ -0,0 +1,3 @@
+-- Revert the claim of webhook event
+
+ALTER TABLE public.payment_webhook_events DROP COLUMN IF EXISTS processing_at;
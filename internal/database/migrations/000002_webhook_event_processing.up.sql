@@ -0,0 +1,4 @@
+-- Claim of a webhook event, a delivery process the event only when it set processing_at and the previous
+-- claim is older than the lease
+
+ALTER TABLE public.payment_webhook_events ADD COLUMN IF NOT EXISTS processing_at timestamptz NULL;
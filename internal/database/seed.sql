@@ -0,0 +1,3 @@
+INSERT INTO users (id,username,"password",full_name,status,"level",created_at,updated_at) VALUES
+	 ('1638070605594742300','admin@admin.com','5f4dcc3b5aa765d61d8327deb882cf99','Admin',1,1,'2023-07-19 10:19:03.043387+00',NULL)
+ON CONFLICT DO NOTHING;
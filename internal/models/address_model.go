@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type Address struct {
+	Id               string     `json:"id"`
+	UserId           string     `json:"user_id"`
+	Label            string     `json:"label" binding:"required"`
+	RecipientName    string     `json:"recipient_name" binding:"required"`
+	PhoneNumber      string     `json:"phone_number" binding:"required"`
+	Address          string     `json:"address" binding:"required"`
+	DistrictAddress  string     `json:"district_address"`
+	CityAddress      string     `json:"city_address"`
+	ProvinceAddress  string     `json:"province_address"`
+	PostalCode       int        `json:"postal_code"`
+	LatitudeAddress  string     `json:"latitude_address"`
+	LongitudeAddress string     `json:"longitude_address"`
+	IsDefault        bool       `json:"is_default"`
+	CreatedAt        *time.Time `json:"created_at"`
+	UpdatedAt        *time.Time `json:"updated_at"`
+}
+
+// OrderAddress copy of the address at the time the order is made,
+// later change on the saved address does not change the order
+type OrderAddress struct {
+	Label            string `json:"label"`
+	RecipientName    string `json:"recipient_name"`
+	PhoneNumber      string `json:"phone_number"`
+	Address          string `json:"address"`
+	DistrictAddress  string `json:"district_address"`
+	CityAddress      string `json:"city_address"`
+	ProvinceAddress  string `json:"province_address"`
+	PostalCode       int    `json:"postal_code"`
+	LatitudeAddress  string `json:"latitude_address"`
+	LongitudeAddress string `json:"longitude_address"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type DeadLetterRequest struct {
+	// MessageId message to replay or purge, empty means every message of the queue
+	MessageId []string `json:"message_id"`
+}
+
+type DeadLetterResult struct {
+	Queue string `json:"queue"`
+	Total int    `json:"total"`
+}
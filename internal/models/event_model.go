@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// OrderCreatedEvent payload of order.created
+type OrderCreatedEvent struct {
+	OrderId       string     `json:"order_id" binding:"required"`
+	UserId        string     `json:"user_id"`
+	TotalAmount   int64      `json:"total_amount"`
+	TotalQuantity int        `json:"total_quantity"`
+	PaymentMethod string     `json:"payment_method"`
+	CreatedAt     *time.Time `json:"created_at"`
+}
+
+// OrderActivityEvent field shared by every order.* event, order.payment_requested carry the whole order with its id
+type OrderActivityEvent struct {
+	Id      string `json:"id"`
+	OrderId string `json:"order_id"`
+}
+
+// OrderPaidEvent payload of order.paid
+type OrderPaidEvent struct {
+	OrderId              string     `json:"order_id" binding:"required"`
+	PaymentAcquirementId string     `json:"payment_acquirement_id"`
+	PaymentDate          *time.Time `json:"payment_date"`
+}
+
+// schema version of the message payload, bump it when the json of the payload change incompatibly and register
+// the decoder of the previous version on the consumer, see rabbitmq.HandleVersions
+
+func (Order) SchemaVersion() int { return 1 }
+
+func (OrderCreatedEvent) SchemaVersion() int { return 1 }
+
+func (OrderActivityEvent) SchemaVersion() int { return 1 }
+
+func (OrderPaidEvent) SchemaVersion() int { return 1 }
+
+func (Refund) SchemaVersion() int { return 1 }
+
+func (ExportJob) SchemaVersion() int { return 1 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type ExportOrderFilter struct {
+	DateFrom      string `json:"date_from"`
+	DateTo        string `json:"date_to"`
+	Status        int    `json:"status"`
+	PaymentMethod string `json:"payment_method"`
+	UserId        string `json:"user_id"`
+	Format        string `json:"format"`
+}
+
+type ExportOrderRow struct {
+	OrderId              string     `json:"order_id"`
+	UserId               string     `json:"user_id"`
+	FullName             string     `json:"full_name"`
+	Status               int        `json:"status"`
+	PaymentMethod        string     `json:"payment_method"`
+	PaymentAcquirementId string     `json:"payment_acquirement_id"`
+	PaymentDate          *time.Time `json:"payment_date"`
+	TotalAmount          int64      `json:"total_amount"`
+	TotalQuantity        int        `json:"total_quantity"`
+	TotalDiscountAmount  int64      `json:"total_discount_amount"`
+	CreatedAt            *time.Time `json:"created_at"`
+	OrderItemId          string     `json:"order_item_id"`
+	ItemId               string     `json:"item_id"`
+	ItemName             string     `json:"item_name"`
+	SKU                  string     `json:"sku"`
+	Quantity             int        `json:"quantity"`
+	ItemPrice            int64      `json:"item_price"`
+	DiscountAmount       int64      `json:"discount_amount"`
+}
+
+type ExportJob struct {
+	Id           string            `json:"id"`
+	UserId       string            `json:"user_id"`
+	Format       string            `json:"format"`
+	Filter       ExportOrderFilter `gorm:"serializer:json" json:"filter"`
+	Status       int               `json:"status"`
+	FileName     string            `json:"file_name"`
+	TotalRows    int64             `json:"total_rows"`
+	ErrorMessage string            `json:"error_message"`
+	CreatedAt    *time.Time        `json:"created_at"`
+	UpdatedAt    *time.Time        `json:"updated_at"`
+	FinishedAt   *time.Time        `json:"finished_at"`
+}
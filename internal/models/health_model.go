@@ -7,6 +7,21 @@ type SystemHealth struct {
 
 type ServiceSupport struct {
 	Master bool `json:"master"`
-	Slave  bool `json:"slave"`
-	Redis  bool `json:"redis"`
+	// Slave at least one replica is healthy, otherwise the read go to the master
+	Slave          bool            `json:"slave"`
+	ReadFromMaster bool            `json:"read_from_master"`
+	Replicas       []ReplicaHealth `json:"replicas"`
+	Redis          bool            `json:"redis"`
+}
+
+type ReplicaHealth struct {
+	Host       string  `json:"host"`
+	Healthy    bool    `json:"healthy"`
+	LagSeconds float64 `json:"lag_seconds"`
+	InUse      int     `json:"in_use"`
+	Error      string  `json:"error,omitempty"`
+}
+
+type Readiness struct {
+	Ready bool `json:"ready"`
 }
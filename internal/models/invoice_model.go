@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type Invoice struct {
+	Id                   string        `json:"id"`
+	InvoiceNumber        string        `json:"invoice_number"`
+	OrderId              string        `json:"order_id"`
+	UserId               string        `json:"user_id"`
+	CustomerName         string        `json:"customer_name"`
+	Period               string        `json:"period"`
+	Sequence             int           `json:"sequence"`
+	InvoiceItem          []InvoiceItem `gorm:"-" json:"invoice_item"`
+	SubtotalAmount       int64         `json:"subtotal_amount"`
+	DiscountAmount       int64         `json:"discount_amount"`
+	TaxAmount            int64         `json:"tax_amount"`
+	TotalAmount          int64         `json:"total_amount"`
+	PaymentMethod        string        `json:"payment_method"`
+	PaymentAcquirementId string        `json:"payment_acquirement_id"`
+	PaymentDate          *time.Time    `json:"payment_date"`
+	IssuedAt             *time.Time    `json:"issued_at"`
+	CreatedAt            *time.Time    `json:"created_at"`
+}
+
+type InvoiceItem struct {
+	InvoiceId      string     `json:"invoice_id"`
+	OrderItemId    string     `json:"order_item_id"`
+	ItemId         string     `json:"item_id"`
+	ItemName       string     `json:"item_name"`
+	SKU            string     `json:"sku"`
+	Quantity       int        `json:"quantity"`
+	UnitPrice      int64      `json:"unit_price"`
+	DiscountAmount int64      `json:"discount_amount"`
+	TaxClass       string     `json:"tax_class"`
+	TaxRate        int64      `json:"tax_rate"`
+	PriceInclusive bool       `json:"price_inclusive"`
+	TaxAmount      int64      `json:"tax_amount"`
+	Amount         int64      `json:"amount"`
+	CreatedAt      *time.Time `json:"created_at"`
+}
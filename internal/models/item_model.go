@@ -9,6 +9,7 @@ type Item struct {
 	Price     int64      `json:"Price"`
 	Quantity  int        `json:"quantity"`
 	Stock     int        `json:"stock"`
+	TaxClass  string     `json:"tax_class"`
 	CreatedAt *time.Time `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
 }
@@ -3,18 +3,21 @@ package models
 import "time"
 
 type Order struct {
-	Id                   string      `json:"id"`
-	UserId               string      `json:"user_id"`
-	Status               int         `json:"status"`
-	OrderItem            []OrderItem `json:"order_item"`
-	TotalAmount          int64       `json:"total_amount"`
-	TotalQuantity        int         `json:"total_quantity"`
-	TotalDiscountAmount  int64       `json:"total_discount_amount"`
-	PaymentMethod        string      `json:"payment_method"`
-	PaymentAcquirementId string      `json:"payment_acquirement_id"`
-	PaymentDate          *time.Time  `json:"payment_date"`
-	CreatedAt            *time.Time  `json:"created_at"`
-	UpdatedAt            *time.Time  `json:"updated_at"`
+	Id                   string        `json:"id"`
+	UserId               string        `json:"user_id"`
+	Status               int           `json:"status"`
+	OrderItem            []OrderItem   `json:"order_item"`
+	TotalAmount          int64         `json:"total_amount"`
+	TotalQuantity        int           `json:"total_quantity"`
+	TotalDiscountAmount  int64         `json:"total_discount_amount"`
+	TotalTaxAmount       int64         `json:"total_tax_amount"`
+	AddressId            string        `json:"address_id"`
+	ShippingAddress      *OrderAddress `gorm:"serializer:json" json:"shipping_address"`
+	PaymentMethod        string        `json:"payment_method"`
+	PaymentAcquirementId string        `json:"payment_acquirement_id"`
+	PaymentDate          *time.Time    `json:"payment_date"`
+	CreatedAt            *time.Time    `json:"created_at"`
+	UpdatedAt            *time.Time    `json:"updated_at"`
 }
 
 type OrderItem struct {
@@ -26,6 +29,10 @@ type OrderItem struct {
 	Quantity       int        `json:"quantity"`
 	ItemPrice      int64      `json:"item_price"`
 	DiscountAmount int64      `json:"discount_amount"`
+	TaxClass       string     `json:"tax_class"`
+	TaxRate        int64      `json:"tax_rate"`
+	PriceInclusive bool       `json:"price_inclusive"`
+	TaxAmount      int64      `json:"tax_amount"`
 	CreatedAt      *time.Time `json:"created_at"`
 	UpdatedAt      *time.Time `json:"updated_at"`
 }
@@ -38,6 +45,9 @@ type InsertOrder struct {
 	TotalAmount          int64             `json:"total_amount"`
 	TotalQuantity        int               `json:"total_quantity"`
 	TotalDiscountAmount  int64             `json:"total_discount_amount"`
+	TotalTaxAmount       int64             `json:"total_tax_amount"`
+	AddressId            string            `json:"address_id"`
+	ShippingAddress      *OrderAddress     `gorm:"serializer:json" json:"shipping_address"`
 	PaymentMethod        string            `json:"payment_method"`
 	PaymentAcquirementId string            `json:"payment_acquirement_id"`
 	PaymentDate          *time.Time        `json:"payment_date"`
@@ -52,6 +62,10 @@ type InsertOrderItem struct {
 	Quantity       int        `json:"quantity"`
 	ItemPrice      int64      `json:"item_price"`
 	DiscountAmount int64      `json:"discount_amount"`
+	TaxClass       string     `json:"tax_class"`
+	TaxRate        int64      `json:"tax_rate"`
+	PriceInclusive bool       `json:"price_inclusive"`
+	TaxAmount      int64      `json:"tax_amount"`
 	CreatedAt      *time.Time `json:"created_at"`
 	UpdatedAt      *time.Time `json:"updated_at"`
 }
@@ -63,6 +77,7 @@ type CreateOrder struct {
 		Quantity int    `json:"quantity" binding:"required"`
 	} `json:"order_item"`
 	PaymentMethod string `json:"payment_method" binding:"required"`
+	AddressId     string `json:"address_id"`
 }
 
 type UpdateOrder struct {
@@ -73,6 +88,7 @@ type UpdateOrder struct {
 		Quantity int    `json:"quantity" binding:"required"`
 	} `json:"order_item"`
 	PaymentMethod string `json:"payment_method" binding:"required"`
+	AddressId     string `json:"address_id"`
 }
 
 type OrderLog struct {
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// OutboxMessage message written in the same transaction as the state change, published later by the relay
+type OutboxMessage struct {
+	Id            string     `json:"id"`
+	Topic         string     `json:"topic"`
+	Payload       string     `json:"payload"`
+	Headers       string     `json:"headers"`
+	Status        int        `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	CreatedAt     *time.Time `json:"created_at"`
+	PublishedAt   *time.Time `json:"published_at"`
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type PaymentResult struct {
 	OrderId       string  `json:"order_id"`
 	TotalAmount   float64 `json:"total_amount"`
@@ -10,3 +12,38 @@ type PaymentResult struct {
 type PaymentOrder struct {
 	OrderId string `json:"order_id"`
 }
+
+type PaymentAttempt struct {
+	Id            string     `json:"id"`
+	OrderId       string     `json:"order_id"`
+	Provider      string     `json:"provider"`
+	PaymentMethod string     `json:"payment_method"`
+	Amount        int64      `json:"amount"`
+	Status        string     `json:"status"`
+	Reference     string     `json:"reference"`
+	FailureReason string     `json:"failure_reason"`
+	PaidAt        *time.Time `json:"paid_at"`
+	CreatedAt     *time.Time `json:"created_at"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
+
+type PaymentWebhook struct {
+	EventId       string     `json:"event_id"`
+	OrderId       string     `json:"order_id"`
+	Reference     string     `json:"reference"`
+	Status        string     `json:"status"`
+	Amount        int64      `json:"amount"`
+	FailureReason string     `json:"failure_reason"`
+	PaidAt        *time.Time `json:"paid_at"`
+}
+
+type PaymentWebhookEvent struct {
+	Provider     string     `json:"provider"`
+	EventId      string     `json:"event_id"`
+	OrderId      string     `json:"order_id"`
+	Status       string     `json:"status"`
+	Payload      string     `json:"payload"`
+	ReceivedAt   *time.Time `json:"received_at"`
+	ProcessingAt *time.Time `json:"processing_at"`
+	ProcessedAt  *time.Time `json:"processed_at"`
+}
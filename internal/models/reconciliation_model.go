@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type ReconciliationFilter struct {
+	DateFrom string
+	DateTo   string
+	// Provider settlement report provider, only used with settlement report
+	Provider string
+	// StuckAfter ready to pay order without any payment progress after this duration is re-driven
+	StuckAfter time.Duration
+	Redrive    bool
+}
+
+type ReconciliationDiscrepancy struct {
+	OrderId              string `json:"order_id"`
+	OrderStatus          int    `json:"order_status"`
+	OrderAmount          int64  `json:"order_amount"`
+	PaymentAcquirementId string `json:"payment_acquirement_id"`
+	Provider             string `json:"provider"`
+	Reference            string `json:"reference"`
+	ProviderStatus       string `json:"provider_status"`
+	ProviderAmount       int64  `json:"provider_amount"`
+	Discrepancy          string `json:"discrepancy"`
+	Action               string `json:"action"`
+}
+
+type ReconciliationSummary struct {
+	Checked     int `json:"checked"`
+	Discrepancy int `json:"discrepancy"`
+	Redriven    int `json:"redriven"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+type Refund struct {
+	Id                string       `json:"id"`
+	OrderId           string       `json:"order_id"`
+	PaymentAttemptId  string       `json:"payment_attempt_id"`
+	RefundItem        []RefundItem `gorm:"-" json:"refund_item"`
+	Amount            int64        `json:"amount"`
+	Reason            string       `json:"reason"`
+	Status            int          `json:"status"`
+	ProviderReference string       `json:"provider_reference"`
+	FailureReason     string       `json:"failure_reason"`
+	RequestedBy       string       `json:"requested_by"`
+	CreatedAt         *time.Time   `json:"created_at"`
+	UpdatedAt         *time.Time   `json:"updated_at"`
+	ProcessedAt       *time.Time   `json:"processed_at"`
+}
+
+type RefundItem struct {
+	RefundId    string     `json:"refund_id"`
+	OrderItemId string     `json:"order_item_id"`
+	ItemId      string     `json:"item_id"`
+	Quantity    int        `json:"quantity"`
+	Amount      int64      `json:"amount"`
+	CreatedAt   *time.Time `json:"created_at"`
+}
+
+// RefundOrder refund request, without item and amount the whole remaining order amount is refunded.
+// Item refund return the stock of the item, amount only refund does not
+type RefundOrder struct {
+	OrderId    string `json:"order_id" binding:"required"`
+	Amount     int64  `json:"amount"`
+	Reason     string `json:"reason" binding:"required"`
+	RefundItem []struct {
+		OrderItemId string `json:"order_item_id" binding:"required"`
+		Quantity    int    `json:"quantity" binding:"required"`
+	} `json:"refund_item"`
+}
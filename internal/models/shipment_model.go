@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+type Shipment struct {
+	Id             string     `json:"id"`
+	OrderId        string     `json:"order_id"`
+	Courier        string     `json:"courier"`
+	Service        string     `json:"service"`
+	TrackingNumber string     `json:"tracking_number"`
+	Status         int        `json:"status"`
+	ShippedAt      *time.Time `json:"shipped_at"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	CreatedAt      *time.Time `json:"created_at"`
+	UpdatedAt      *time.Time `json:"updated_at"`
+}
+
+type ShipmentLog struct {
+	ShipmentId     string     `json:"shipment_id"`
+	ShipmentStatus int        `json:"shipment_status"`
+	Note           string     `json:"note"`
+	CreatedAt      *time.Time `json:"created_at"`
+}
+
+type CreateShipment struct {
+	OrderId        string `json:"order_id" binding:"required"`
+	Courier        string `json:"courier" binding:"required"`
+	Service        string `json:"service"`
+	TrackingNumber string `json:"tracking_number" binding:"required"`
+}
+
+type UpdateShipmentStatus struct {
+	ShipmentId string `json:"shipment_id" binding:"required"`
+	Status     int    `json:"status" binding:"required"`
+	Note       string `json:"note"`
+}
@@ -0,0 +1,151 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IAddressRepository interface {
+	GetAddressById(ctx context.Context, addressId string) (models.Address, error)
+	GetAddressByUserId(ctx context.Context, userId string) ([]models.Address, error)
+	GetDefaultAddress(ctx context.Context, userId string) (models.Address, error)
+	CreateAddress(ctx context.Context, address models.Address) error
+	UpdateAddress(ctx context.Context, address models.Address) error
+	DeleteAddress(ctx context.Context, addressId string) error
+}
+
+type AddressRepository struct {
+	Master   gorm.IGormMaster
+	Slave    gorm.IGormSlave
+	Redis    redis.Iredis
+	Rabbitmq rabbitmq.IRabbitMQ
+}
+
+func NewAddressRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis redis.Iredis, rabbitmq rabbitmq.IRabbitMQ) IAddressRepository {
+	return &AddressRepository{
+		Master:   master,
+		Slave:    slave,
+		Redis:    redis,
+		Rabbitmq: rabbitmq,
+	}
+}
+
+func (r *AddressRepository) GetAddressById(ctx context.Context, addressId string) (models.Address, error) {
+	var address models.Address
+
+	err := r.Slave.WithContext(ctx).
+		Where("id = ?", addressId).First(&address)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.Address{}, err
+	}
+
+	return address, nil
+}
+
+func (r *AddressRepository) GetAddressByUserId(ctx context.Context, userId string) ([]models.Address, error) {
+	var address []models.Address = make([]models.Address, 0)
+
+	err := r.Slave.WithContext(ctx).
+		Where("user_id = ?", userId).Find(&address)
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return address, err
+	}
+
+	return address, nil
+}
+
+func (r *AddressRepository) GetDefaultAddress(ctx context.Context, userId string) (models.Address, error) {
+	var address models.Address
+
+	err := r.Slave.WithContext(ctx).
+		Where("user_id = ? AND is_default = true", userId).First(&address)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.Address{}, err
+	}
+
+	return address, nil
+}
+
+func (r *AddressRepository) CreateAddress(ctx context.Context, address models.Address) error {
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+
+	// only one default address per user
+	if address.IsDefault {
+		err := tx.Exec("UPDATE addresses SET is_default = false WHERE user_id = ?", address.UserId).Error
+		if err != nil {
+			tx.Rollback()
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return err
+		}
+	}
+
+	err := tx.Table("addresses").Create(&address).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *AddressRepository) UpdateAddress(ctx context.Context, address models.Address) error {
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+
+	// only one default address per user
+	if address.IsDefault {
+		err := tx.Exec("UPDATE addresses SET is_default = false WHERE user_id = ? AND id <> ?", address.UserId, address.Id).Error
+		if err != nil {
+			tx.Rollback()
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return err
+		}
+	}
+
+	err := tx.Table("addresses").Where("id = ?", address.Id).
+		Select("label", "recipient_name", "phone_number", "address", "district_address", "city_address", "province_address",
+			"postal_code", "latitude_address", "longitude_address", "is_default", "updated_at").
+		Updates(&address).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *AddressRepository) DeleteAddress(ctx context.Context, addressId string) error {
+
+	err := r.Master.WithContext(ctx).DB().Exec("DELETE FROM addresses WHERE id = ?", addressId).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IDeadLetterRepository interface {
+	ListDeadLetter(ctx context.Context, queue string, limit int) ([]rabbitmq.DeadLetterMessage, error)
+	ReplayDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error)
+	PurgeDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error)
+}
+
+type DeadLetterRepository struct {
+	Master   gorm.IGormMaster
+	Slave    gorm.IGormSlave
+	Redis    redis.Iredis
+	Rabbitmq rabbitmq.IRabbitMQ
+}
+
+func NewDeadLetterRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis redis.Iredis, rabbitmq rabbitmq.IRabbitMQ) IDeadLetterRepository {
+	return &DeadLetterRepository{
+		Master:   master,
+		Slave:    slave,
+		Redis:    redis,
+		Rabbitmq: rabbitmq,
+	}
+}
+
+func (r *DeadLetterRepository) ListDeadLetter(ctx context.Context, queue string, limit int) ([]rabbitmq.DeadLetterMessage, error) {
+	message, err := r.Rabbitmq.ListDeadLetter(ctx, queue, limit)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return message, err
+	}
+
+	return message, nil
+}
+
+func (r *DeadLetterRepository) ReplayDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error) {
+	replayed, err := r.Rabbitmq.ReplayDeadLetter(ctx, queue, messageIds)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return replayed, err
+	}
+
+	logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("%d message of %s replayed", replayed, rabbitmq.DeadLetterQueue(queue))
+
+	return replayed, nil
+}
+
+func (r *DeadLetterRepository) PurgeDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error) {
+	purged, err := r.Rabbitmq.PurgeDeadLetter(ctx, queue, messageIds)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return purged, err
+	}
+
+	logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("%d message of %s purged", purged, rabbitmq.DeadLetterQueue(queue))
+
+	return purged, nil
+}
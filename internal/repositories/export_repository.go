@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IExportRepository interface {
+	StreamOrderExport(ctx context.Context, filter models.ExportOrderFilter, fn func(row models.ExportOrderRow) error) (int64, error)
+	GetExportJobById(ctx context.Context, jobId string) (models.ExportJob, error)
+	CreateExportJob(ctx context.Context, job models.ExportJob) error
+	UpdateExportJob(ctx context.Context, job models.ExportJob) error
+}
+
+type ExportRepository struct {
+	Master   gorm.IGormMaster
+	Slave    gorm.IGormSlave
+	Redis    redis.Iredis
+	Rabbitmq rabbitmq.IRabbitMQ
+}
+
+func NewExportRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis redis.Iredis, rabbitmq rabbitmq.IRabbitMQ) IExportRepository {
+	return &ExportRepository{
+		Master:   master,
+		Slave:    slave,
+		Redis:    redis,
+		Rabbitmq: rabbitmq,
+	}
+}
+
+func buildOrderExportFilter(filter models.ExportOrderFilter) (string, []interface{}) {
+	where := "o.created_at >= ? AND o.created_at <= ?"
+	args := []interface{}{filter.DateFrom + " 00:00:00", filter.DateTo + " 23:59:59"}
+
+	if filter.Status > 0 {
+		where += " AND o.status = ?"
+		args = append(args, filter.Status)
+	}
+
+	if filter.PaymentMethod != "" {
+		where += " AND o.payment_method = ?"
+		args = append(args, filter.PaymentMethod)
+	}
+
+	if filter.UserId != "" {
+		where += " AND o.user_id = ?"
+		args = append(args, filter.UserId)
+	}
+
+	return where, args
+}
+
+// StreamOrderExport iterate orders joined with their items row by row, fn is called for every row
+// so the caller can write it out without keeping the result set in memory
+func (r *ExportRepository) StreamOrderExport(ctx context.Context, filter models.ExportOrderFilter, fn func(row models.ExportOrderRow) error) (int64, error) {
+	var total int64
+
+	where, args := buildOrderExportFilter(filter)
+
+	db := r.Slave.WithContext(ctx).DB()
+	rows, err := db.Raw(`SELECT o.id AS order_id, o.user_id, COALESCE(u.full_name, '') AS full_name, o.status, o.payment_method,
+			COALESCE(o.payment_acquirement_id, '') AS payment_acquirement_id, o.payment_date, o.total_amount, o.total_quantity,
+			COALESCE(o.total_discount_amount, 0) AS total_discount_amount, o.created_at,
+			COALESCE(oi.id, '') AS order_item_id, COALESCE(oi.item_id, '') AS item_id, COALESCE(i.item_name, '') AS item_name,
+			COALESCE(i.sku, '') AS sku, COALESCE(oi.quantity, 0) AS quantity, COALESCE(oi.item_price, 0) AS item_price,
+			COALESCE(oi.discount_amount, 0) AS discount_amount
+		FROM orders o
+		LEFT JOIN order_items oi ON oi.order_id = o.id
+		LEFT JOIN items i ON i.id = oi.item_id
+		LEFT JOIN users u ON u.id = o.user_id
+		WHERE `+where+`
+		ORDER BY o.created_at, o.id, oi.id`, args...).Rows()
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.ExportOrderRow
+
+		err = db.ScanRows(rows, &row)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return total, err
+		}
+
+		err = fn(row)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return total, err
+		}
+
+		total++
+	}
+
+	if err = rows.Err(); err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return total, err
+	}
+
+	return total, nil
+}
+
+func (r *ExportRepository) GetExportJobById(ctx context.Context, jobId string) (models.ExportJob, error) {
+	var job models.ExportJob
+
+	err := r.Master.WithContext(ctx).
+		Where(`"export_jobs"."id" = ?`, jobId).First(&job)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.ExportJob{}, err
+	}
+
+	return job, nil
+}
+
+// CreateExportJob store the job and queue it to the export consumer in one transaction
+func (r *ExportRepository) CreateExportJob(ctx context.Context, job models.ExportJob) error {
+	outbox, err := newOutboxMessage(ctx, helper.EventExportRequested, job)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+	err = tx.Table("export_jobs").Create(&job).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	err = tx.Table("outbox_messages").Create(&outbox).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+func (r *ExportRepository) UpdateExportJob(ctx context.Context, job models.ExportJob) error {
+
+	err := r.Master.WithContext(ctx).DB().Table("export_jobs").Where("id = ?", job.Id).
+		Select("status", "file_name", "total_rows", "error_message", "updated_at", "finished_at").
+		Updates(&job).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
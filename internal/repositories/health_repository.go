@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
 	"github.com/galihfebrizki/dbo-api/utils/gorm"
 	"github.com/galihfebrizki/dbo-api/utils/redis"
 
@@ -13,6 +14,7 @@ import (
 type IHealthRepository interface {
 	CheckDBMaster(ctx context.Context) bool
 	CheckDBSlave(ctx context.Context) bool
+	CheckDBReplicas(ctx context.Context) []models.ReplicaHealth
 	CheckRedis(ctx context.Context) bool
 }
 
@@ -52,6 +54,22 @@ func (r *HealthRepository) CheckDBSlave(ctx context.Context) bool {
 	return true
 }
 
+// CheckDBReplicas implements IHealthRepository, the state of the last health check of every replica
+func (r *HealthRepository) CheckDBReplicas(ctx context.Context) []models.ReplicaHealth {
+	replicas := make([]models.ReplicaHealth, 0)
+	for _, status := range r.Slave.Replicas() {
+		replicas = append(replicas, models.ReplicaHealth{
+			Host:       status.Host,
+			Healthy:    status.Healthy,
+			LagSeconds: status.Lag.Seconds(),
+			InUse:      status.InUse,
+			Error:      status.Error,
+		})
+	}
+
+	return replicas
+}
+
 // CheckRedis implements IHealthRepository
 func (r *HealthRepository) CheckRedis(ctx context.Context) bool {
 	err := r.Redis.Ping(ctx)
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	"github.com/sirupsen/logrus"
+	grm "gorm.io/gorm"
+)
+
+type IInvoiceRepository interface {
+	GetInvoiceByOrderId(ctx context.Context, orderId string) (models.Invoice, error)
+	GetInvoiceItemByInvoiceId(ctx context.Context, invoiceId string) ([]models.InvoiceItem, error)
+	CreateInvoice(ctx context.Context, invoice models.Invoice) (models.Invoice, error)
+}
+
+type InvoiceRepository struct {
+	Master   gorm.IGormMaster
+	Slave    gorm.IGormSlave
+	Redis    redis.Iredis
+	Rabbitmq rabbitmq.IRabbitMQ
+}
+
+func NewInvoiceRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis redis.Iredis, rabbitmq rabbitmq.IRabbitMQ) IInvoiceRepository {
+	return &InvoiceRepository{
+		Master:   master,
+		Slave:    slave,
+		Redis:    redis,
+		Rabbitmq: rabbitmq,
+	}
+}
+
+func (r *InvoiceRepository) GetInvoiceByOrderId(ctx context.Context, orderId string) (models.Invoice, error) {
+	var invoice models.Invoice
+
+	err := r.Slave.WithContext(ctx).
+		Where(`"invoices"."order_id" = ?`, orderId).First(&invoice)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.Invoice{}, err
+	}
+
+	return invoice, nil
+}
+
+func (r *InvoiceRepository) GetInvoiceItemByInvoiceId(ctx context.Context, invoiceId string) ([]models.InvoiceItem, error) {
+	var items []models.InvoiceItem = make([]models.InvoiceItem, 0)
+
+	err := r.Slave.WithContext(ctx).
+		Where("invoice_id = ?", invoiceId).Find(&items)
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return items, err
+	}
+
+	return items, nil
+}
+
+// CreateInvoice take the next number of the invoice period and store the invoice in one transaction,
+// the sequence row stay locked until commit and a rollback also revert the counter so numbers are gapless
+func (r *InvoiceRepository) CreateInvoice(ctx context.Context, invoice models.Invoice) (models.Invoice, error) {
+
+	err := r.Master.WithContext(ctx).DB().Transaction(func(tx *grm.DB) error {
+		var sequence int
+
+		err := tx.Raw(`INSERT INTO invoice_sequences (period, last_number, updated_at) VALUES (?, 1, now())
+			ON CONFLICT (period) DO UPDATE SET last_number = invoice_sequences.last_number + 1, updated_at = now()
+			RETURNING last_number`, invoice.Period).Scan(&sequence).Error
+		if err != nil {
+			return err
+		}
+
+		invoice.Sequence = sequence
+		invoice.InvoiceNumber = fmt.Sprintf(helper.InvoiceNumberFormat, invoice.Period, sequence)
+
+		err = tx.Table("invoices").Create(&invoice).Error
+		if err != nil {
+			return err
+		}
+
+		for i := range invoice.InvoiceItem {
+			invoice.InvoiceItem[i].InvoiceId = invoice.Id
+		}
+
+		if len(invoice.InvoiceItem) > 0 {
+			err = tx.Table("invoice_items").Create(&invoice.InvoiceItem).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return models.Invoice{}, err
+	}
+
+	return invoice, nil
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/galihfebrizki/dbo-api/config"
 	"github.com/galihfebrizki/dbo-api/helper"
@@ -20,12 +21,16 @@ type IOrderRepository interface {
 	GetOrderItemByOrderId(ctx context.Context, orderId string) ([]models.OrderItem, error)
 	GetOrderByUserId(ctx context.Context, userId string) ([]models.Order, error)
 	GetOrderPagination(ctx context.Context, page int, rowPerPage int, dateFrom string, dateTo string) ([]models.Order, int, error)
+	GetOrderByStatus(ctx context.Context, status []int, dateFrom string, dateTo string) ([]models.Order, error)
 	CreateOrder(ctx context.Context, order models.InsertOrder) error
 	UpdateOrder(ctx context.Context, order models.InsertOrder) error
 	DeleteOrder(ctx context.Context, orderId string) error
 	DeleteOrderItem(ctx context.Context, orderItemId string) error
 	SearchOrder(ctx context.Context, querySearch string) ([]models.Order, error)
 	UpdateStatusOrder(ctx context.Context, status int, orderId string) error
+	UpdatePaymentOrder(ctx context.Context, orderId string, acquirementId string, paymentDate *time.Time) error
+	FailPaymentOrder(ctx context.Context, orderId string) error
+	CompleteOrder(ctx context.Context, orderId string) error
 	InsertLog(ctx context.Context, dataLog models.OrderLog) error
 }
 
@@ -133,10 +138,40 @@ func (r *OrderRepository) GetOrderPagination(ctx context.Context, page int, rowP
 	return orders, count, nil
 }
 
+// GetOrderByStatus order with one of the status created within the date range, read from master
+// because reconciliation act on the current status
+func (r *OrderRepository) GetOrderByStatus(ctx context.Context, status []int, dateFrom string, dateTo string) ([]models.Order, error) {
+	var order []models.Order = make([]models.Order, 0)
+
+	err := r.Master.WithContext(ctx).DB().
+		Where("status IN ? AND created_at >= ? and created_at <= ?", status, dateFrom+" 00:00:00", dateTo+" 23:59:59").
+		Order("created_at").Find(&order).Error
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return order, err
+	}
+
+	return order, nil
+}
+
+// CreateOrder insert the order with its item and queue the order.created event in the same transaction
 func (r *OrderRepository) CreateOrder(ctx context.Context, order models.InsertOrder) error {
+	outbox, err := newOutboxMessage(ctx, helper.EventOrderCreated, models.OrderCreatedEvent{
+		OrderId:       order.Id,
+		UserId:        order.UserId,
+		TotalAmount:   order.TotalAmount,
+		TotalQuantity: order.TotalQuantity,
+		PaymentMethod: order.PaymentMethod,
+		CreatedAt:     order.CreatedAt,
+	})
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
 
 	tx := r.Master.WithContext(ctx).DB().Begin()
-	err := tx.Table("orders").Create(&order).Error
+	err = tx.Table("orders").Create(&order).Error
 
 	if err != nil {
 		tx.Rollback()
@@ -152,13 +187,22 @@ func (r *OrderRepository) CreateOrder(ctx context.Context, order models.InsertOr
 		return err
 	}
 
+	err = tx.Table("outbox_messages").Create(&outbox).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
 func (r *OrderRepository) UpdateOrder(ctx context.Context, order models.InsertOrder) error {
 
 	tx := r.Master.WithContext(ctx).DB().Begin()
-	err := tx.Table("orders").Where("id = ?", order.Id).Updates(&order).Error
+	err := tx.Table("orders").Where("id = ?", order.Id).
+		Select("user_id", "status", "total_amount", "total_quantity", "total_discount_amount", "total_tax_amount", "address_id", "shipping_address", "payment_method", "updated_at").
+		Updates(&order).Error
 	if err != nil {
 		tx.Rollback()
 		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
@@ -167,7 +211,7 @@ func (r *OrderRepository) UpdateOrder(ctx context.Context, order models.InsertOr
 
 	err = tx.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"item_id", "quantity", "item_price", "discount_amount", "updated_at"}),
+		DoUpdates: clause.AssignmentColumns([]string{"item_id", "quantity", "item_price", "discount_amount", "tax_class", "tax_rate", "price_inclusive", "tax_amount", "updated_at"}),
 	}).Table("order_items").Save(&order.OrderItem).Error
 	if err != nil {
 		tx.Rollback()
@@ -243,7 +287,7 @@ func (r *OrderRepository) SearchOrder(ctx context.Context, querySearch string) (
 
 func (r *OrderRepository) UpdateStatusOrder(ctx context.Context, status int, orderId string) error {
 	err := r.Master.WithContext(ctx).DB().
-		Exec(`UPDATE orders SET status = ? WHERE id = ?`, status, orderId).Error
+		Exec(`UPDATE orders SET status = ?, updated_at = ? WHERE id = ?`, status, time.Now(), orderId).Error
 	if err != nil {
 		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
 		return err
@@ -252,6 +296,129 @@ func (r *OrderRepository) UpdateStatusOrder(ctx context.Context, status int, ord
 	return nil
 }
 
+// UpdatePaymentOrder mark order as paid with the reference of the payment provider, write the order log and queue
+// the order.paid event in the same transaction. Only an order still ready to pay is updated, nothing is written
+// for an order already settled
+func (r *OrderRepository) UpdatePaymentOrder(ctx context.Context, orderId string, acquirementId string, paymentDate *time.Time) error {
+	outbox, err := newOutboxMessage(ctx, helper.EventOrderPaid, models.OrderPaidEvent{
+		OrderId:              orderId,
+		PaymentAcquirementId: acquirementId,
+		PaymentDate:          paymentDate,
+	})
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	currentTime := time.Now()
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+	db := tx.Exec(`UPDATE orders SET status = ?, payment_acquirement_id = ?, payment_date = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		helper.StatusPaid, acquirementId, paymentDate, currentTime, orderId, helper.StatusReadyToPay)
+	if db.Error != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(db.Error)
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("order %s is not ready to pay, payment ignored", orderId)
+		return nil
+	}
+
+	err = tx.Table("order_logs").Create(&models.OrderLog{
+		OrderId:     orderId,
+		OrderStatus: helper.StatusPaid,
+		CreatedAt:   &currentTime,
+		UpdatedAt:   &currentTime,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	err = tx.Table("outbox_messages").Create(&outbox).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// FailPaymentOrder mark order as failed and write the order log in the same transaction, only an order still
+// ready to pay is updated
+func (r *OrderRepository) FailPaymentOrder(ctx context.Context, orderId string) error {
+	currentTime := time.Now()
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+	db := tx.Exec(`UPDATE orders SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		helper.StatusFailed, currentTime, orderId, helper.StatusReadyToPay)
+	if db.Error != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(db.Error)
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("order %s is not ready to pay, failure ignored", orderId)
+		return nil
+	}
+
+	err := tx.Table("order_logs").Create(&models.OrderLog{
+		OrderId:     orderId,
+		OrderStatus: helper.StatusFailed,
+		CreatedAt:   &currentTime,
+		UpdatedAt:   &currentTime,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// CompleteOrder mark a paid order as success and write the order log in the same transaction, nothing is written
+// for an order that is not paid anymore, e.g. completed by a concurrent delivery or refunded
+func (r *OrderRepository) CompleteOrder(ctx context.Context, orderId string) error {
+	currentTime := time.Now()
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+	db := tx.Exec(`UPDATE orders SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		helper.StatusSuccess, currentTime, orderId, helper.StatusPaid)
+	if db.Error != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(db.Error)
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("order %s is not paid, completion ignored", orderId)
+		return nil
+	}
+
+	err := tx.Table("order_logs").Create(&models.OrderLog{
+		OrderId:     orderId,
+		OrderStatus: helper.StatusSuccess,
+		CreatedAt:   &currentTime,
+		UpdatedAt:   &currentTime,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
 func (r *OrderRepository) InsertLog(ctx context.Context, dataLog models.OrderLog) error {
 
 	err := r.Master.WithContext(ctx).DB().Table("order_logs").Create(&dataLog).Error
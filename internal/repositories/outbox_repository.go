@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+	utils "github.com/galihfebrizki/dbo-api/utils/snowflake"
+
+	"github.com/sirupsen/logrus"
+)
+
+// outboxLease time a claimed message is hidden from other relay while it is being published
+const outboxLease = time.Minute
+
+type IOutboxRepository interface {
+	ClaimOutbox(ctx context.Context, limit int) ([]models.OutboxMessage, error)
+	PublishOutbox(ctx context.Context, message models.OutboxMessage) error
+	MarkOutboxPublished(ctx context.Context, message models.OutboxMessage) error
+	MarkOutboxFailed(ctx context.Context, message models.OutboxMessage) error
+}
+
+type OutboxRepository struct {
+	Master   gorm.IGormMaster
+	Slave    gorm.IGormSlave
+	Redis    redis.Iredis
+	Rabbitmq rabbitmq.IRabbitMQ
+}
+
+func NewOutboxRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis redis.Iredis, rabbitmq rabbitmq.IRabbitMQ) IOutboxRepository {
+	return &OutboxRepository{
+		Master:   master,
+		Slave:    slave,
+		Redis:    redis,
+		Rabbitmq: rabbitmq,
+	}
+}
+
+// newOutboxMessage build the outbox row of a message, the caller insert it in the transaction of the state change.
+// The envelope is built now so the message keep the correlation and trace of the request that caused it
+func newOutboxMessage(ctx context.Context, topic string, body interface{}) (models.OutboxMessage, error) {
+	message, err := rabbitmq.NewMessage(ctx, config.Get().Name, topic, body)
+	if err != nil {
+		return models.OutboxMessage{}, err
+	}
+
+	headers, err := json.Marshal(message.Envelope)
+	if err != nil {
+		return models.OutboxMessage{}, err
+	}
+
+	currentTime := time.Now()
+
+	return models.OutboxMessage{
+		Id:            utils.GenerateSnowflakeOutbox(),
+		Topic:         topic,
+		Payload:       string(message.Body),
+		Headers:       string(headers),
+		Status:        helper.OutboxPending,
+		NextAttemptAt: &currentTime,
+		CreatedAt:     &currentTime,
+	}, nil
+}
+
+// ClaimOutbox lock pending message that is due, concurrent relay skip the message claimed by the others
+func (r *OutboxRepository) ClaimOutbox(ctx context.Context, limit int) ([]models.OutboxMessage, error) {
+	var message []models.OutboxMessage = make([]models.OutboxMessage, 0)
+
+	currentTime := time.Now()
+
+	err := r.Master.WithContext(ctx).
+		Raw(`UPDATE outbox_messages SET next_attempt_at = ? WHERE id IN (
+				SELECT id FROM outbox_messages WHERE status = ? AND next_attempt_at <= ?
+				ORDER BY created_at LIMIT ? FOR UPDATE SKIP LOCKED
+			) RETURNING *`, &message, currentTime.Add(outboxLease), helper.OutboxPending, currentTime, limit)
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return message, err
+	}
+
+	// returning does not keep the order of the sub query
+	sort.Slice(message, func(i, j int) bool {
+		return message[i].CreatedAt.Before(*message[j].CreatedAt)
+	})
+
+	return message, nil
+}
+
+// PublishOutbox publish the stored payload as is with its envelope to the event exchange with the topic as routing key,
+// the outbox id become the message id. A message stored before the envelope is published as version 1
+func (r *OutboxRepository) PublishOutbox(ctx context.Context, message models.OutboxMessage) error {
+	ctx = helper.SetRequestIDToContext(ctx, message.Id)
+
+	envelope := rabbitmq.Envelope{}
+	if message.Headers != "" {
+		err := json.Unmarshal([]byte(message.Headers), &envelope)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return err
+		}
+	}
+
+	err := r.Rabbitmq.PublishEvent(ctx, helper.EventExchange, message.Topic, rabbitmq.Message{
+		Envelope: envelope,
+		Body:     []byte(message.Payload),
+	})
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) MarkOutboxPublished(ctx context.Context, message models.OutboxMessage) error {
+	err := r.Master.WithContext(ctx).DB().
+		Exec("UPDATE outbox_messages SET status = ?, attempts = ?, published_at = ? WHERE id = ?",
+			helper.OutboxPublished, message.Attempts, message.PublishedAt, message.Id).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) MarkOutboxFailed(ctx context.Context, message models.OutboxMessage) error {
+	err := r.Master.WithContext(ctx).DB().
+		Exec("UPDATE outbox_messages SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+			message.Status, message.Attempts, message.LastError, message.NextAttemptAt, message.Id).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
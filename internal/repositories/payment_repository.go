@@ -2,6 +2,8 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/galihfebrizki/dbo-api/helper"
 	"github.com/galihfebrizki/dbo-api/internal/models"
@@ -13,9 +15,22 @@ import (
 )
 
 type IPaymentRepository interface {
+	SendOrderToPayment(ctx context.Context, orderMsg models.Order, dataLog models.OrderLog) error
 	PublishOrderToPayment(ctx context.Context, orderMsg models.Order) error
+	GetLatestPaymentAttempt(ctx context.Context, orderId string) (models.PaymentAttempt, error)
+	GetPaymentAttemptById(ctx context.Context, attemptId string) (models.PaymentAttempt, error)
+	CreatePaymentAttempt(ctx context.Context, attempt models.PaymentAttempt) error
+	UpdatePaymentAttempt(ctx context.Context, attempt models.PaymentAttempt) error
+	GetPaymentAttemptByReference(ctx context.Context, provider string, reference string) (models.PaymentAttempt, error)
+	CreateWebhookEvent(ctx context.Context, event models.PaymentWebhookEvent) (bool, error)
+	ReleaseWebhookEvent(ctx context.Context, provider string, eventId string) error
+	UpdateWebhookEventProcessed(ctx context.Context, provider string, eventId string) error
 }
 
+// webhookEventLease time a delivery hold the claim of a webhook event, a delivery that crashed while processing
+// the event is taken over by the next delivery after the lease
+const webhookEventLease = time.Minute
+
 type PaymentRepository struct {
 	Master   gorm.IGormMaster
 	Slave    gorm.IGormSlave
@@ -32,8 +47,187 @@ func NewPaymentRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis
 	}
 }
 
+// SendOrderToPayment move created order to ready to pay and queue the payment message in one transaction,
+// return record not found when the order is no longer in created status
+func (r *PaymentRepository) SendOrderToPayment(ctx context.Context, orderMsg models.Order, dataLog models.OrderLog) error {
+	outbox, err := newOutboxMessage(ctx, helper.EventPaymentRequested, orderMsg)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+	db := tx.Exec("UPDATE orders SET status = ?, updated_at = ? WHERE id = ? AND status = ?",
+		helper.StatusReadyToPay, dataLog.CreatedAt, orderMsg.Id, helper.StatusCreate)
+	if db.Error != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(db.Error)
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Info(gorm.ErrRecordNotFound)
+		return gorm.ErrRecordNotFound
+	}
+
+	err = tx.Table("order_logs").Create(&dataLog).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	err = tx.Table("outbox_messages").Create(&outbox).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// PublishOrderToPayment queue the payment message of an order that is already ready to pay
 func (r *PaymentRepository) PublishOrderToPayment(ctx context.Context, orderMsg models.Order) error {
-	err := r.Rabbitmq.PublishMessage(ctx, helper.PaymentProccess, orderMsg)
+	outbox, err := newOutboxMessage(ctx, helper.EventPaymentRequested, orderMsg)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	err = r.Master.WithContext(ctx).DB().Table("outbox_messages").Create(&outbox).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *PaymentRepository) GetLatestPaymentAttempt(ctx context.Context, orderId string) (models.PaymentAttempt, error) {
+	var attempt models.PaymentAttempt
+
+	err := r.Master.WithContext(ctx).DB().
+		Where("order_id = ?", orderId).Order("created_at DESC").First(&attempt).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.PaymentAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+func (r *PaymentRepository) GetPaymentAttemptById(ctx context.Context, attemptId string) (models.PaymentAttempt, error) {
+	var attempt models.PaymentAttempt
+
+	err := r.Master.WithContext(ctx).
+		Where("id = ?", attemptId).First(&attempt)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.PaymentAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+func (r *PaymentRepository) CreatePaymentAttempt(ctx context.Context, attempt models.PaymentAttempt) error {
+	err := r.Master.WithContext(ctx).DB().Table("payment_attempts").Create(&attempt).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *PaymentRepository) UpdatePaymentAttempt(ctx context.Context, attempt models.PaymentAttempt) error {
+	err := r.Master.WithContext(ctx).DB().Table("payment_attempts").Where("id = ?", attempt.Id).
+		Select("status", "reference", "failure_reason", "paid_at", "updated_at").
+		Updates(&attempt).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *PaymentRepository) GetPaymentAttemptByReference(ctx context.Context, provider string, reference string) (models.PaymentAttempt, error) {
+	var attempt models.PaymentAttempt
+
+	err := r.Master.WithContext(ctx).
+		Where("provider = ? AND reference = ?", provider, reference).First(&attempt)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.PaymentAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+// CreateWebhookEvent record the event once per provider event id and claim it, return false when the event
+// has already been processed or another delivery claimed it within the lease
+func (r *PaymentRepository) CreateWebhookEvent(ctx context.Context, event models.PaymentWebhookEvent) (bool, error) {
+	db := r.Master.WithContext(ctx).DB().
+		Exec(`INSERT INTO payment_webhook_events (provider, event_id, order_id, status, payload, received_at, processing_at)
+			VALUES (?, ?, ?, ?, ?, ?, now()) ON CONFLICT (provider, event_id) DO NOTHING`,
+			event.Provider, event.EventId, event.OrderId, event.Status, event.Payload, event.ReceivedAt)
+	if db.Error != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(db.Error)
+		return false, db.Error
+	}
+
+	if db.RowsAffected > 0 {
+		return true, nil
+	}
+
+	// event received before, claim it again only when the previous delivery did not finish and its claim expired,
+	// the update is atomic so only one concurrent delivery win the claim
+	db = r.Master.WithContext(ctx).DB().
+		Exec(`UPDATE payment_webhook_events SET processing_at = now()
+			WHERE provider = ? AND event_id = ? AND processed_at IS NULL
+			AND (processing_at IS NULL OR processing_at < now() - ? * interval '1 second')`,
+			event.Provider, event.EventId, int(webhookEventLease.Seconds()))
+	if db.Error != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(db.Error)
+		return false, db.Error
+	}
+
+	return db.RowsAffected > 0, nil
+}
+
+// ReleaseWebhookEvent drop the claim of an event that was not processed, the next delivery claim it without
+// waiting for the lease
+func (r *PaymentRepository) ReleaseWebhookEvent(ctx context.Context, provider string, eventId string) error {
+	err := r.Master.WithContext(ctx).DB().
+		Exec("UPDATE payment_webhook_events SET processing_at = NULL WHERE provider = ? AND event_id = ? AND processed_at IS NULL", provider, eventId).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *PaymentRepository) UpdateWebhookEventProcessed(ctx context.Context, provider string, eventId string) error {
+	err := r.Master.WithContext(ctx).DB().
+		Exec("UPDATE payment_webhook_events SET processed_at = ? WHERE provider = ? AND event_id = ?", time.Now(), provider, eventId).Error
 	if err != nil {
 		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
 		return err
@@ -0,0 +1,262 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IRefundRepository interface {
+	GetRefundById(ctx context.Context, refundId string) (models.Refund, error)
+	GetRefundByOrderId(ctx context.Context, orderId string) ([]models.Refund, error)
+	GetPendingRefund(ctx context.Context, dateFrom string, dateTo string) ([]models.Refund, error)
+	GetRefundItemByRefundId(ctx context.Context, refundId string) ([]models.RefundItem, error)
+	GetRefundItemByOrderId(ctx context.Context, orderId string) ([]models.RefundItem, error)
+	CreateRefund(ctx context.Context, refund models.Refund) error
+	CompleteRefund(ctx context.Context, refund models.Refund, orderStatus int) error
+	FailRefund(ctx context.Context, refund models.Refund) error
+}
+
+// ErrRefundPending another refund of the order is still pending
+var ErrRefundPending = errors.New("another refund of the order is pending")
+
+// ErrRefundAmount the refund exceed the amount of the order not refunded yet
+var ErrRefundAmount = errors.New("refund amount exceed the remaining amount")
+
+type RefundRepository struct {
+	Master   gorm.IGormMaster
+	Slave    gorm.IGormSlave
+	Redis    redis.Iredis
+	Rabbitmq rabbitmq.IRabbitMQ
+}
+
+func NewRefundRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis redis.Iredis, rabbitmq rabbitmq.IRabbitMQ) IRefundRepository {
+	return &RefundRepository{
+		Master:   master,
+		Slave:    slave,
+		Redis:    redis,
+		Rabbitmq: rabbitmq,
+	}
+}
+
+func (r *RefundRepository) GetRefundById(ctx context.Context, refundId string) (models.Refund, error) {
+	var refund models.Refund
+
+	// read from master, consumer may receive the message before replica has the refund
+	err := r.Master.WithContext(ctx).
+		Where("id = ?", refundId).First(&refund)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.Refund{}, err
+	}
+
+	return refund, nil
+}
+
+func (r *RefundRepository) GetRefundByOrderId(ctx context.Context, orderId string) ([]models.Refund, error) {
+	var refund []models.Refund = make([]models.Refund, 0)
+
+	err := r.Master.WithContext(ctx).
+		Where("order_id = ?", orderId).Find(&refund)
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return refund, err
+	}
+
+	return refund, nil
+}
+
+// GetPendingRefund refund still pending created in the date range
+func (r *RefundRepository) GetPendingRefund(ctx context.Context, dateFrom string, dateTo string) ([]models.Refund, error) {
+	var refund []models.Refund = make([]models.Refund, 0)
+
+	err := r.Master.WithContext(ctx).DB().
+		Where("status = ? AND created_at >= ? and created_at <= ?", helper.RefundPending, dateFrom+" 00:00:00", dateTo+" 23:59:59").
+		Order("created_at").Find(&refund).Error
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return refund, err
+	}
+
+	return refund, nil
+}
+
+func (r *RefundRepository) GetRefundItemByRefundId(ctx context.Context, refundId string) ([]models.RefundItem, error) {
+	var refundItem []models.RefundItem = make([]models.RefundItem, 0)
+
+	err := r.Master.WithContext(ctx).
+		Where("refund_id = ?", refundId).Find(&refundItem)
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return refundItem, err
+	}
+
+	return refundItem, nil
+}
+
+// GetRefundItemByOrderId refunded item of an order, failed refund are excluded
+func (r *RefundRepository) GetRefundItemByOrderId(ctx context.Context, orderId string) ([]models.RefundItem, error) {
+	var refundItem []models.RefundItem = make([]models.RefundItem, 0)
+
+	err := r.Master.WithContext(ctx).
+		Raw(`SELECT ri.* FROM refund_items ri JOIN refunds rf ON rf.id = ri.refund_id
+			WHERE rf.order_id = ? AND rf.status <> ?`, &refundItem, orderId, helper.RefundFailed)
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return refundItem, err
+	}
+
+	return refundItem, nil
+}
+
+// CreateRefund store the refund and queue it to the refund consumer in one transaction
+func (r *RefundRepository) CreateRefund(ctx context.Context, refund models.Refund) error {
+	outbox, err := newOutboxMessage(ctx, helper.EventRefundRequested, refund)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+
+	// the order row is locked until the commit so the refund of the same order are created one after the other,
+	// the pending refund and the remaining amount read before the lock are checked again
+	var totalAmount int64
+	err = tx.Raw("SELECT total_amount FROM orders WHERE id = ? FOR UPDATE", refund.OrderId).Scan(&totalAmount).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	var previous struct {
+		Pending  int64
+		Refunded int64
+	}
+	err = tx.Raw(`SELECT COUNT(*) FILTER (WHERE status = ?) AS pending, COALESCE(SUM(amount) FILTER (WHERE status = ?), 0) AS refunded
+		FROM refunds WHERE order_id = ?`, helper.RefundPending, helper.RefundSuccess, refund.OrderId).Scan(&previous).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	if previous.Pending > 0 {
+		tx.Rollback()
+		return ErrRefundPending
+	}
+	if previous.Refunded+refund.Amount > totalAmount {
+		tx.Rollback()
+		return ErrRefundAmount
+	}
+
+	err = tx.Table("refunds").Create(&refund).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	if len(refund.RefundItem) > 0 {
+		err = tx.Table("refund_items").Create(&refund.RefundItem).Error
+		if err != nil {
+			tx.Rollback()
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return err
+		}
+	}
+
+	err = tx.Table("outbox_messages").Create(&outbox).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// CompleteRefund mark refund as success, return the stock of refunded item and update the order status. Only a
+// pending refund is completed, nothing is written when another delivery already completed or failed it
+func (r *RefundRepository) CompleteRefund(ctx context.Context, refund models.Refund, orderStatus int) error {
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+	db := tx.Table("refunds").Where("id = ? AND status = ?", refund.Id, helper.RefundPending).
+		Select("status", "provider_reference", "failure_reason", "updated_at", "processed_at").
+		Updates(&refund)
+	if db.Error != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(db.Error)
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("refund %s is not pending anymore, completion ignored", refund.Id)
+		return nil
+	}
+
+	var err error
+
+	for _, ri := range refund.RefundItem {
+		err = tx.Exec("UPDATE items SET stock = stock + ?, updated_at = ? WHERE id = ?", ri.Quantity, refund.UpdatedAt, ri.ItemId).Error
+		if err != nil {
+			tx.Rollback()
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return err
+		}
+	}
+
+	err = tx.Exec("UPDATE orders SET status = ?, updated_at = ? WHERE id = ?", orderStatus, refund.UpdatedAt, refund.OrderId).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	err = tx.Table("order_logs").Create(&models.OrderLog{
+		OrderId:     refund.OrderId,
+		OrderStatus: orderStatus,
+		CreatedAt:   refund.UpdatedAt,
+		UpdatedAt:   refund.UpdatedAt,
+	}).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// FailRefund mark a pending refund as failed
+func (r *RefundRepository) FailRefund(ctx context.Context, refund models.Refund) error {
+	currentTime := time.Now()
+
+	err := r.Master.WithContext(ctx).DB().
+		Exec("UPDATE refunds SET status = ?, provider_reference = ?, failure_reason = ?, updated_at = ?, processed_at = ? WHERE id = ? AND status = ?",
+			helper.RefundFailed, refund.ProviderReference, refund.FailureReason, currentTime, currentTime, refund.Id, helper.RefundPending).Error
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IShipmentRepository interface {
+	GetShipmentById(ctx context.Context, shipmentId string) (models.Shipment, error)
+	GetShipmentByOrderId(ctx context.Context, orderId string) ([]models.Shipment, error)
+	CreateShipment(ctx context.Context, shipment models.Shipment, dataLog models.ShipmentLog) error
+	UpdateShipmentStatus(ctx context.Context, shipment models.Shipment, fromStatus int, dataLog models.ShipmentLog) error
+}
+
+// ErrShipmentStatusChanged the shipment is not in the expected status anymore, e.g. updated by a concurrent request
+var ErrShipmentStatusChanged = errors.New("shipment status has changed")
+
+type ShipmentRepository struct {
+	Master   gorm.IGormMaster
+	Slave    gorm.IGormSlave
+	Redis    redis.Iredis
+	Rabbitmq rabbitmq.IRabbitMQ
+}
+
+func NewShipmentRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis redis.Iredis, rabbitmq rabbitmq.IRabbitMQ) IShipmentRepository {
+	return &ShipmentRepository{
+		Master:   master,
+		Slave:    slave,
+		Redis:    redis,
+		Rabbitmq: rabbitmq,
+	}
+}
+
+func (r *ShipmentRepository) GetShipmentById(ctx context.Context, shipmentId string) (models.Shipment, error) {
+	var shipment models.Shipment
+
+	// read from master, status transition must see the latest status
+	err := r.Master.WithContext(ctx).
+		Where("id = ?", shipmentId).First(&shipment)
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Info(err)
+		} else {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return models.Shipment{}, err
+	}
+
+	return shipment, nil
+}
+
+func (r *ShipmentRepository) GetShipmentByOrderId(ctx context.Context, orderId string) ([]models.Shipment, error) {
+	var shipment []models.Shipment = make([]models.Shipment, 0)
+
+	err := r.Slave.WithContext(ctx).
+		Where("order_id = ?", orderId).Find(&shipment)
+
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return shipment, err
+	}
+
+	return shipment, nil
+}
+
+func (r *ShipmentRepository) CreateShipment(ctx context.Context, shipment models.Shipment, dataLog models.ShipmentLog) error {
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+	err := tx.Table("shipments").Create(&shipment).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	err = tx.Table("shipment_logs").Create(&dataLog).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// UpdateShipmentStatus move the shipment from fromStatus to its status and write the shipment log in the same transaction,
+// ErrShipmentStatusChanged is returned and nothing is written when the shipment is not in fromStatus anymore
+func (r *ShipmentRepository) UpdateShipmentStatus(ctx context.Context, shipment models.Shipment, fromStatus int, dataLog models.ShipmentLog) error {
+
+	tx := r.Master.WithContext(ctx).DB().Begin()
+	db := tx.Table("shipments").Where("id = ? AND status = ?", shipment.Id, fromStatus).
+		Select("status", "shipped_at", "delivered_at", "updated_at").
+		Updates(&shipment)
+	if db.Error != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(db.Error)
+		return db.Error
+	}
+
+	if db.RowsAffected == 0 {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("shipment %s is not in status %d anymore", shipment.Id, fromStatus)
+		return ErrShipmentStatusChanged
+	}
+
+	err := tx.Table("shipment_logs").Create(&dataLog).Error
+	if err != nil {
+		tx.Rollback()
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+	"github.com/galihfebrizki/dbo-api/utils/tax"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ITaxRepository interface {
+	GetTaxClasses(ctx context.Context) (map[string]tax.Class, error)
+}
+
+type TaxRepository struct {
+	Master   gorm.IGormMaster
+	Slave    gorm.IGormSlave
+	Redis    redis.Iredis
+	Rabbitmq rabbitmq.IRabbitMQ
+}
+
+func NewTaxRepository(master gorm.IGormMaster, slave gorm.IGormSlave, redis redis.Iredis, rabbitmq rabbitmq.IRabbitMQ) ITaxRepository {
+	return &TaxRepository{
+		Master:   master,
+		Slave:    slave,
+		Redis:    redis,
+		Rabbitmq: rabbitmq,
+	}
+}
+
+func (r *TaxRepository) GetTaxClasses(ctx context.Context) (map[string]tax.Class, error) {
+	var (
+		classes    []tax.Class
+		taxClasses = map[string]tax.Class{}
+	)
+
+	// get data from redis
+	err := r.Redis.Get(ctx, "tax_classes", &classes)
+	if err != nil {
+		err = r.Slave.WithContext(ctx).
+			Table("tax_classes").Find(&classes)
+
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return taxClasses, err
+		}
+
+		// set data to redis
+		err = r.Redis.Set(ctx, "tax_classes", classes, config.GetCacheTime())
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Errorf("set redis error: %s", err)
+		}
+	}
+
+	for _, class := range classes {
+		taxClasses[class.Code] = class
+	}
+
+	return taxClasses, nil
+}
@@ -15,6 +15,24 @@ var enmapscode = map[int]string{
 	1010:  "Cannot deleting this order",
 	1011:  "Cannot updating this order",
 	1012:  "Cannot pay this order",
+	1013:  "Failed to create export job",
+	1014:  "Export job not found",
+	1015:  "Export file is not ready",
+	1016:  "Failed to generate invoice",
+	1017:  "Invoice is only available for paid order",
+	1019:  "Address not found",
+	1020:  "Shipment is only available for paid order",
+	1021:  "Invalid shipment status transition",
+	1022:  "Shipment not found",
+	1023:  "Invalid webhook signature",
+	1024:  "Payment attempt not found",
+	1025:  "Paid amount does not match order amount",
+	1026:  "Cannot refund this order",
+	1027:  "Another refund of this order is still in process",
+	1028:  "Invalid refund amount",
+	1029:  "Unknown queue",
+	1030:  "Failed to calculate order tax",
+	1031:  "Request body too large",
 	-1018: "Order not found",
 }
 
@@ -37,6 +55,24 @@ var idmapscode = map[int]string{
 	1010:  "Order ini tidak bisa di hapus",
 	1011:  "Order ini tidak bisa di ubah",
 	1012:  "Order ini tidak bisa di bayar",
+	1013:  "Gagal membuat proses ekspor",
+	1014:  "Proses ekspor tidak ditemukan",
+	1015:  "File ekspor belum tersedia",
+	1016:  "Gagal membuat invoice",
+	1017:  "Invoice hanya tersedia untuk order yang sudah dibayar",
+	1019:  "Alamat tidak ditemukan",
+	1020:  "Pengiriman hanya tersedia untuk order yang sudah dibayar",
+	1021:  "Perubahan status pengiriman tidak valid",
+	1022:  "Pengiriman tidak ditemukan",
+	1023:  "Tanda tangan webhook tidak valid",
+	1024:  "Percobaan pembayaran tidak ditemukan",
+	1025:  "Jumlah pembayaran tidak sesuai dengan jumlah order",
+	1026:  "Order ini tidak bisa di refund",
+	1027:  "Refund lain untuk order ini masih diproses",
+	1028:  "Jumlah refund tidak valid",
+	1029:  "Antrian tidak dikenal",
+	1030:  "Gagal menghitung pajak order",
+	1031:  "Ukuran permintaan terlalu besar",
 	-1018: "Pesanan tidak ditemukan",
 }
 
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	utils "github.com/galihfebrizki/dbo-api/utils/snowflake"
+)
+
+type IAddressService interface {
+	GetAddress(ctx context.Context, userId string) (int, responses.GenericResponse)
+	CreateAddress(ctx context.Context, userId string, address models.Address) (int, responses.GenericResponse)
+	UpdateAddress(ctx context.Context, userId string, address models.Address) (int, responses.GenericResponse)
+	DeleteAddress(ctx context.Context, userId string, addressId string) (int, responses.GenericResponse)
+	GetOrderAddress(ctx context.Context, userId string, addressId string) (string, *models.OrderAddress, error)
+}
+
+type AddressService struct {
+	AddressRepository repositories.IAddressRepository
+}
+
+func NewAddressService(repository repositories.IAddressRepository) IAddressService {
+	return &AddressService{
+		AddressRepository: repository,
+	}
+}
+
+func (s *AddressService) GetAddress(ctx context.Context, userId string) (int, responses.GenericResponse) {
+	address, err := s.AddressRepository.GetAddressByUserId(ctx, userId)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, address)
+}
+
+func (s *AddressService) CreateAddress(ctx context.Context, userId string, address models.Address) (int, responses.GenericResponse) {
+	currentTime := time.Now()
+
+	address.Id = utils.GenerateSnowflakeAddress()
+	address.UserId = userId
+	address.CreatedAt = &currentTime
+	address.UpdatedAt = nil
+
+	// first address of the user become the default address
+	_, err := s.AddressRepository.GetDefaultAddress(ctx, userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		address.IsDefault = true
+	}
+
+	err = s.AddressRepository.CreateAddress(ctx, address)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, address)
+}
+
+func (s *AddressService) UpdateAddress(ctx context.Context, userId string, address models.Address) (int, responses.GenericResponse) {
+	before, err := s.AddressRepository.GetAddressById(ctx, address.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(1019, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	if before.UserId != userId {
+		return http.StatusOK, *responses.NewGenericResponse(1019, nil)
+	}
+
+	currentTime := time.Now()
+
+	address.UserId = userId
+	address.CreatedAt = before.CreatedAt
+	address.UpdatedAt = &currentTime
+
+	err = s.AddressRepository.UpdateAddress(ctx, address)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, address)
+}
+
+func (s *AddressService) DeleteAddress(ctx context.Context, userId string, addressId string) (int, responses.GenericResponse) {
+	address, err := s.AddressRepository.GetAddressById(ctx, addressId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(1019, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	if address.UserId != userId {
+		return http.StatusOK, *responses.NewGenericResponse(1019, nil)
+	}
+
+	// order keep their own copy of the address, so deleting is safe
+	err = s.AddressRepository.DeleteAddress(ctx, addressId)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, nil)
+}
+
+// GetOrderAddress resolve the address to be copied to an order, without address id the default address is used.
+// Return empty id and nil address when the user has no address at all
+func (s *AddressService) GetOrderAddress(ctx context.Context, userId string, addressId string) (string, *models.OrderAddress, error) {
+	var (
+		address models.Address
+		err     error
+	)
+
+	if addressId == "" {
+		address, err = s.AddressRepository.GetDefaultAddress(ctx, userId)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", nil, nil
+		}
+	} else {
+		address, err = s.AddressRepository.GetAddressById(ctx, addressId)
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	if address.UserId != userId {
+		return "", nil, gorm.ErrRecordNotFound
+	}
+
+	return address.Id, &models.OrderAddress{
+		Label:            address.Label,
+		RecipientName:    address.RecipientName,
+		PhoneNumber:      address.PhoneNumber,
+		Address:          address.Address,
+		DistrictAddress:  address.DistrictAddress,
+		CityAddress:      address.CityAddress,
+		ProvinceAddress:  address.ProvinceAddress,
+		PostalCode:       address.PostalCode,
+		LatitudeAddress:  address.LatitudeAddress,
+		LongitudeAddress: address.LongitudeAddress,
+	}, nil
+}
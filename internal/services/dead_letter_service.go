@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+)
+
+// deadLetterQueue queue consumed by this service, only their dead letter queue can be managed
+var deadLetterQueue = map[string]bool{
+	helper.PaymentProccess: true,
+	helper.OrderExport:     true,
+	helper.RefundProccess:  true,
+	helper.InvoiceGenerate: true,
+}
+
+type IDeadLetterService interface {
+	ListDeadLetter(ctx context.Context, queue string, limit int) (int, responses.GenericResponse)
+	ReplayDeadLetter(ctx context.Context, queue string, request models.DeadLetterRequest) (int, responses.GenericResponse)
+	PurgeDeadLetter(ctx context.Context, queue string, request models.DeadLetterRequest) (int, responses.GenericResponse)
+}
+
+type DeadLetterService struct {
+	DeadLetterRepository repositories.IDeadLetterRepository
+}
+
+func NewDeadLetterService(repository repositories.IDeadLetterRepository) IDeadLetterService {
+	return &DeadLetterService{
+		DeadLetterRepository: repository,
+	}
+}
+
+func (s *DeadLetterService) ListDeadLetter(ctx context.Context, queue string, limit int) (int, responses.GenericResponse) {
+	if !deadLetterQueue[queue] {
+		return http.StatusBadRequest, *responses.NewGenericResponse(1029, nil)
+	}
+
+	message, err := s.DeadLetterRepository.ListDeadLetter(ctx, queue, limit)
+	if err != nil {
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1008, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, message)
+}
+
+func (s *DeadLetterService) ReplayDeadLetter(ctx context.Context, queue string, request models.DeadLetterRequest) (int, responses.GenericResponse) {
+	if !deadLetterQueue[queue] {
+		return http.StatusBadRequest, *responses.NewGenericResponse(1029, nil)
+	}
+
+	replayed, err := s.DeadLetterRepository.ReplayDeadLetter(ctx, queue, request.MessageId)
+	if err != nil {
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1008, models.DeadLetterResult{Queue: queue, Total: replayed})
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, models.DeadLetterResult{Queue: queue, Total: replayed})
+}
+
+func (s *DeadLetterService) PurgeDeadLetter(ctx context.Context, queue string, request models.DeadLetterRequest) (int, responses.GenericResponse) {
+	if !deadLetterQueue[queue] {
+		return http.StatusBadRequest, *responses.NewGenericResponse(1029, nil)
+	}
+
+	purged, err := s.DeadLetterRepository.PurgeDeadLetter(ctx, queue, request.MessageId)
+	if err != nil {
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1008, models.DeadLetterResult{Queue: queue, Total: purged})
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, models.DeadLetterResult{Queue: queue, Total: purged})
+}
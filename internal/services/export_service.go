@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/utils/export"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	utils "github.com/galihfebrizki/dbo-api/utils/snowflake"
+
+	"github.com/sirupsen/logrus"
+)
+
+var exportOrderHeader = []interface{}{
+	"order_id", "user_id", "full_name", "status", "payment_method", "payment_acquirement_id", "payment_date",
+	"total_amount", "total_quantity", "total_discount_amount", "created_at",
+	"order_item_id", "item_id", "item_name", "sku", "quantity", "item_price", "discount_amount",
+}
+
+type IExportService interface {
+	IsAsyncExport(filter models.ExportOrderFilter) bool
+	ExportOrder(ctx context.Context, filter models.ExportOrderFilter, w io.Writer) error
+	CreateExportJob(ctx context.Context, userId string, filter models.ExportOrderFilter) (int, responses.GenericResponse)
+	GetExportJob(ctx context.Context, jobId string) (int, responses.GenericResponse)
+	GetExportFile(ctx context.Context, jobId string) (string, string, error)
+	ExportOrderJobReceived(ctx context.Context, job models.ExportJob) error
+}
+
+type ExportService struct {
+	ExportRepository repositories.IExportRepository
+}
+
+func NewExportService(repository repositories.IExportRepository) IExportService {
+	return &ExportService{
+		ExportRepository: repository,
+	}
+}
+
+// IsAsyncExport large date range are exported by the consumer into storage instead of the http response
+func (s *ExportService) IsAsyncExport(filter models.ExportOrderFilter) bool {
+	layout := "2006-01-02"
+
+	dateFrom, err := time.Parse(layout, filter.DateFrom)
+	if err != nil {
+		return false
+	}
+
+	dateTo, err := time.Parse(layout, filter.DateTo)
+	if err != nil {
+		return false
+	}
+
+	days := int(dateTo.Sub(dateFrom).Hours()/24) + 1
+
+	return days > config.Get().Export.AsyncThresholdDays
+}
+
+func (s *ExportService) ExportOrder(ctx context.Context, filter models.ExportOrderFilter, w io.Writer) error {
+	_, err := s.writeOrderExport(ctx, filter, w)
+	return err
+}
+
+func (s *ExportService) writeOrderExport(ctx context.Context, filter models.ExportOrderFilter, w io.Writer) (int64, error) {
+	writer, err := export.NewWriter(filter.Format, w)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return 0, err
+	}
+
+	err = writer.Write(exportOrderHeader)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return 0, err
+	}
+
+	flushRows := config.Get().Export.FlushRows
+	written := 0
+
+	total, err := s.ExportRepository.StreamOrderExport(ctx, filter, func(row models.ExportOrderRow) error {
+		err := writer.Write([]interface{}{
+			row.OrderId, row.UserId, row.FullName, row.Status, row.PaymentMethod, row.PaymentAcquirementId, row.PaymentDate,
+			row.TotalAmount, row.TotalQuantity, row.TotalDiscountAmount, row.CreatedAt,
+			row.OrderItemId, row.ItemId, row.ItemName, row.SKU, row.Quantity, row.ItemPrice, row.DiscountAmount,
+		})
+		if err != nil {
+			return err
+		}
+
+		// push buffered rows to the destination periodically
+		written++
+		if flushRows > 0 && written%flushRows == 0 {
+			return writer.Flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return total, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return total, err
+	}
+
+	return total, nil
+}
+
+func (s *ExportService) CreateExportJob(ctx context.Context, userId string, filter models.ExportOrderFilter) (int, responses.GenericResponse) {
+	currentTime := time.Now()
+
+	job := models.ExportJob{
+		Id:        utils.GenerateSnowflakeExport(),
+		UserId:    userId,
+		Format:    filter.Format,
+		Filter:    filter,
+		Status:    helper.ExportJobPending,
+		CreatedAt: &currentTime,
+	}
+
+	err := s.ExportRepository.CreateExportJob(ctx, job)
+	if err != nil {
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1013, nil)
+	}
+
+	return http.StatusAccepted, *responses.NewGenericResponse(0, job)
+}
+
+func (s *ExportService) GetExportJob(ctx context.Context, jobId string) (int, responses.GenericResponse) {
+	job, err := s.ExportRepository.GetExportJobById(ctx, jobId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(1014, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, job)
+}
+
+// GetExportFile return the stored file path and its download name of a finished export job
+func (s *ExportService) GetExportFile(ctx context.Context, jobId string) (string, string, error) {
+	job, err := s.ExportRepository.GetExportJobById(ctx, jobId)
+	if err != nil {
+		return "", "", err
+	}
+
+	if job.Status != helper.ExportJobDone || job.FileName == "" {
+		return "", "", errors.New(responses.GetErrorCodeEN(1015))
+	}
+
+	downloadName := "orders_" + job.Filter.DateFrom + "_" + job.Filter.DateTo + "." + job.Format
+
+	return filepath.Join(config.Get().Export.StorageDir, job.FileName), downloadName, nil
+}
+
+func (s *ExportService) ExportOrderJobReceived(ctx context.Context, job models.ExportJob) error {
+
+	// check job are exist
+	job, err := s.ExportRepository.GetExportJobById(ctx, job.Id)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return nil
+	}
+
+	if job.Status != helper.ExportJobPending {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("export job %s already proccessed", job.Id)
+		return nil
+	}
+
+	currentTime := time.Now()
+	job.Status = helper.ExportJobProcessing
+	job.UpdatedAt = &currentTime
+
+	err = s.ExportRepository.UpdateExportJob(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	total, err := s.writeOrderExportFile(ctx, job)
+
+	currentTime = time.Now()
+	job.UpdatedAt = &currentTime
+	job.FinishedAt = &currentTime
+	job.TotalRows = total
+
+	if err != nil {
+		job.Status = helper.ExportJobFailed
+		job.ErrorMessage = err.Error()
+	} else {
+		job.Status = helper.ExportJobDone
+		job.FileName = job.Id + "." + job.Format
+	}
+
+	return s.ExportRepository.UpdateExportJob(ctx, job)
+}
+
+func (s *ExportService) writeOrderExportFile(ctx context.Context, job models.ExportJob) (int64, error) {
+	storageDir := config.Get().Export.StorageDir
+
+	err := os.MkdirAll(storageDir, 0o755)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return 0, err
+	}
+
+	// write into temporary file first so a partial file never downloadable
+	tmpFile, err := os.CreateTemp(storageDir, job.Id+"-*.tmp")
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return 0, err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	total, err := s.writeOrderExport(ctx, job.Filter, tmpFile)
+	if err != nil {
+		tmpFile.Close()
+		return total, err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return total, err
+	}
+
+	err = os.Rename(tmpFile.Name(), filepath.Join(storageDir, job.Id+"."+job.Format))
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return total, err
+	}
+
+	return total, nil
+}
@@ -24,12 +24,17 @@ func NewHealthService(repository repositories.IHealthRepository) IHealthService
 
 // Health implements IHealthService
 func (s *HealthService) HealthCheck(ctx context.Context) models.SystemHealth {
+	// the slave check refresh the state of every replica
+	slave := s.HealthRepository.CheckDBSlave(ctx)
+
 	return models.SystemHealth{
 		Version: config.Get().Version,
 		ServiceSupport: models.ServiceSupport{
-			Master: s.HealthRepository.CheckDBMaster(ctx),
-			Slave:  s.HealthRepository.CheckDBSlave(ctx),
-			Redis:  s.HealthRepository.CheckRedis(ctx),
+			Master:         s.HealthRepository.CheckDBMaster(ctx),
+			Slave:          slave,
+			ReadFromMaster: !slave,
+			Replicas:       s.HealthRepository.CheckDBReplicas(ctx),
+			Redis:          s.HealthRepository.CheckRedis(ctx),
 		},
 	}
 }
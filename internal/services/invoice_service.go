@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/internal/templates"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/pdf"
+	utils "github.com/galihfebrizki/dbo-api/utils/snowflake"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	InvoiceFormatHTML = "html"
+	InvoiceFormatPDF  = "pdf"
+)
+
+var invoiceFuncs = map[string]interface{}{
+	"rupiah": formatRupiah,
+	"date":   formatDate,
+	"inc":    func(i int) int { return i + 1 },
+	"line":   func(n int) string { return strings.Repeat("-", n) },
+	"rate":   formatRate,
+}
+
+var (
+	invoiceHTMLTemplate = htmltemplate.Must(htmltemplate.New(templates.InvoiceHTML).Funcs(invoiceFuncs).ParseFS(templates.FS, templates.InvoiceHTML))
+	invoiceTextTemplate = template.Must(template.New(templates.InvoiceText).Funcs(invoiceFuncs).ParseFS(templates.FS, templates.InvoiceText))
+)
+
+type IInvoiceService interface {
+	GenerateInvoice(ctx context.Context, orderId string) (models.Invoice, error)
+	GetInvoice(ctx context.Context, orderId string, userId string) (int, responses.GenericResponse)
+	RenderInvoice(ctx context.Context, invoice models.Invoice, format string, w io.Writer) error
+}
+
+type InvoiceService struct {
+	InvoiceRepository repositories.IInvoiceRepository
+	OrderRepository   repositories.IOrderRepository
+	UserRepository    repositories.IUserRepository
+	UserService       IUserService
+}
+
+func NewInvoiceService(repository repositories.IInvoiceRepository, orderRepository repositories.IOrderRepository, userRepository repositories.IUserRepository, userService IUserService) IInvoiceService {
+	return &InvoiceService{
+		InvoiceRepository: repository,
+		OrderRepository:   orderRepository,
+		UserRepository:    userRepository,
+		UserService:       userService,
+	}
+}
+
+// GenerateInvoice create the invoice of a paid order, calling it again for the same order return the existing invoice
+func (s *InvoiceService) GenerateInvoice(ctx context.Context, orderId string) (models.Invoice, error) {
+	invoice, err := s.getInvoice(ctx, orderId)
+	if err == nil {
+		return invoice, nil
+	}
+
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.Invoice{}, err
+	}
+
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, orderId)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+
+	if order.Status != helper.StatusPaid && order.Status != helper.StatusSuccess {
+		return models.Invoice{}, errors.New(responses.GetErrorCodeEN(1017))
+	}
+
+	orderItem, err := s.OrderRepository.GetOrderItemByOrderId(ctx, orderId)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+
+	user, err := s.UserRepository.GetUserByUserId(ctx, order.UserId)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+	}
+
+	currentTime := time.Now()
+
+	invoice = models.Invoice{
+		Id:                   utils.GenerateSnowflakeInvoice(),
+		OrderId:              order.Id,
+		UserId:               order.UserId,
+		CustomerName:         user.FullName,
+		Period:               currentTime.Format("200601"),
+		PaymentMethod:        order.PaymentMethod,
+		PaymentAcquirementId: order.PaymentAcquirementId,
+		PaymentDate:          order.PaymentDate,
+		IssuedAt:             &currentTime,
+		CreatedAt:            &currentTime,
+	}
+
+	for _, oi := range orderItem {
+		// item_price on order item is the line price (price * quantity)
+		unitPrice := oi.ItemPrice
+		if oi.Quantity > 0 {
+			unitPrice = oi.ItemPrice / int64(oi.Quantity)
+		}
+
+		// exclusive tax is added on top of the line, inclusive tax is already part of the price
+		amount := oi.ItemPrice - oi.DiscountAmount
+		if !oi.PriceInclusive {
+			amount += oi.TaxAmount
+		}
+
+		invoice.InvoiceItem = append(invoice.InvoiceItem, models.InvoiceItem{
+			OrderItemId:    oi.Id,
+			ItemId:         oi.ItemId,
+			ItemName:       oi.ItemName,
+			SKU:            oi.SKU,
+			Quantity:       oi.Quantity,
+			UnitPrice:      unitPrice,
+			DiscountAmount: oi.DiscountAmount,
+			TaxClass:       oi.TaxClass,
+			TaxRate:        oi.TaxRate,
+			PriceInclusive: oi.PriceInclusive,
+			TaxAmount:      oi.TaxAmount,
+			Amount:         amount,
+			CreatedAt:      &currentTime,
+		})
+
+		invoice.SubtotalAmount += oi.ItemPrice
+		invoice.DiscountAmount += oi.DiscountAmount
+		invoice.TaxAmount += oi.TaxAmount
+		invoice.TotalAmount += amount
+	}
+
+	invoice.DiscountAmount += order.TotalDiscountAmount
+	invoice.TotalAmount -= order.TotalDiscountAmount
+
+	invoice, err = s.InvoiceRepository.CreateInvoice(ctx, invoice)
+	if err != nil {
+		// another worker may have created the invoice for this order in the meantime
+		existing, errGet := s.getInvoice(ctx, orderId)
+		if errGet == nil {
+			return existing, nil
+		}
+		return models.Invoice{}, err
+	}
+
+	logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("invoice %s generated for order %s", invoice.InvoiceNumber, orderId)
+
+	return invoice, nil
+}
+
+func (s *InvoiceService) getInvoice(ctx context.Context, orderId string) (models.Invoice, error) {
+	invoice, err := s.InvoiceRepository.GetInvoiceByOrderId(ctx, orderId)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+
+	invoice.InvoiceItem, err = s.InvoiceRepository.GetInvoiceItemByInvoiceId(ctx, invoice.Id)
+	if err != nil {
+		return models.Invoice{}, err
+	}
+
+	return invoice, nil
+}
+
+func (s *InvoiceService) GetInvoice(ctx context.Context, orderId string, userId string) (int, responses.GenericResponse) {
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, orderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(-1018, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	isSuperUser := s.UserService.IsSuperUser(ctx, userId)
+	if !isSuperUser && order.UserId != userId {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error("Unauthorized User")
+		return http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil)
+	}
+
+	if order.Status != helper.StatusPaid && order.Status != helper.StatusSuccess {
+		return http.StatusOK, *responses.NewGenericResponse(1017, nil)
+	}
+
+	// order paid before invoicing existed get their invoice on first request
+	invoice, err := s.GenerateInvoice(ctx, orderId)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1016, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, invoice)
+}
+
+func (s *InvoiceService) RenderInvoice(ctx context.Context, invoice models.Invoice, format string, w io.Writer) error {
+	switch format {
+	case InvoiceFormatHTML:
+		err := invoiceHTMLTemplate.Execute(w, invoice)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return err
+	case InvoiceFormatPDF:
+		var text bytes.Buffer
+
+		err := invoiceTextTemplate.Execute(&text, invoice)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return err
+		}
+
+		err = pdf.Render(w, "Invoice "+invoice.InvoiceNumber, strings.Split(text.String(), "\n"))
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+		return err
+	default:
+		return errors.New("unsupported invoice format")
+	}
+}
+
+// formatRupiah format amount with dot thousand separator, ex: Rp 1.250.000
+func formatRupiah(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	digits := strconv.FormatInt(amount, 10)
+
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+
+	return sign + "Rp " + b.String()
+}
+
+// formatRate format basis point tax rate as percentage, ex: 1100 -> 11%
+func formatRate(bps int64) string {
+	rate := strconv.FormatFloat(float64(bps)/100, 'f', -1, 64)
+	return rate + "%"
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+
+	return t.Format("02-01-2006 15:04")
+}
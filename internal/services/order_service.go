@@ -30,13 +30,17 @@ type OrderService struct {
 	OrderRepository repositories.IOrderRepository
 	ItemRepository  repositories.IItemRepository
 	UserService     IUserService
+	TaxService      ITaxService
+	AddressService  IAddressService
 }
 
-func NewOrderService(repository repositories.IOrderRepository, itemRepository repositories.IItemRepository, userService IUserService) IOrderService {
+func NewOrderService(repository repositories.IOrderRepository, itemRepository repositories.IItemRepository, userService IUserService, taxService ITaxService, addressService IAddressService) IOrderService {
 	return &OrderService{
 		OrderRepository: repository,
 		ItemRepository:  itemRepository,
 		UserService:     userService,
+		TaxService:      taxService,
+		AddressService:  addressService,
 	}
 }
 
@@ -118,6 +122,19 @@ func (s *OrderService) CreateOrder(ctx context.Context, order models.CreateOrder
 		CreatedAt:     &currentTime,
 	}
 
+	// shipping address is copied to the order
+	addressId, shippingAddress, err := s.AddressService.GetOrderAddress(ctx, order.UserId, order.AddressId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(1019, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	dataOrder.AddressId = addressId
+	dataOrder.ShippingAddress = shippingAddress
+
 	for _, oi := range order.OrderItem {
 		item, err := s.ItemRepository.GetItemByItemId(ctx, oi.ItemId)
 		if err != nil {
@@ -134,16 +151,22 @@ func (s *OrderService) CreateOrder(ctx context.Context, order models.CreateOrder
 			ItemId:    oi.ItemId,
 			Quantity:  oi.Quantity,
 			ItemPrice: (item.Price * int64(oi.Quantity)),
+			TaxClass:  item.TaxClass,
 			CreatedAt: &currentTime,
 		})
 
-		dataOrder.TotalAmount += (item.Price * int64(oi.Quantity))
 		dataOrder.TotalQuantity += oi.Quantity
 	}
 
 	dataOrder.OrderItem = orderItem
 
-	err := s.OrderRepository.CreateOrder(ctx, dataOrder)
+	// total amount include the tax of every item
+	err = s.TaxService.ApplyOrderTax(ctx, &dataOrder)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1030, nil)
+	}
+
+	err = s.OrderRepository.CreateOrder(ctx, dataOrder)
 	if err != nil {
 		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
 	}
@@ -184,11 +207,25 @@ func (s *OrderService) UpdateOrder(ctx context.Context, order models.UpdateOrder
 	currentTime := time.Now()
 	orderItem := []models.InsertOrderItem{}
 	dataOrder := models.InsertOrder{
-		Id:            order.OrderId,
-		UserId:        order.UserId,
-		Status:        helper.StatusCreate,
-		PaymentMethod: order.PaymentMethod,
-		CreatedAt:     &currentTime,
+		Id:              order.OrderId,
+		UserId:          order.UserId,
+		Status:          helper.StatusCreate,
+		PaymentMethod:   order.PaymentMethod,
+		AddressId:       beforeOrder.AddressId,
+		ShippingAddress: beforeOrder.ShippingAddress,
+		CreatedAt:       &currentTime,
+	}
+
+	// keep the previous shipping address unless another address is chosen
+	if order.AddressId != "" {
+		dataOrder.AddressId, dataOrder.ShippingAddress, err = s.AddressService.GetOrderAddress(ctx, order.UserId, order.AddressId)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return http.StatusOK, *responses.NewGenericResponse(1019, nil)
+			} else {
+				return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+			}
+		}
 	}
 
 	if len(beforeOrderItem) > len(order.OrderItem) {
@@ -225,11 +262,11 @@ func (s *OrderService) UpdateOrder(ctx context.Context, order models.UpdateOrder
 			ItemId:    oi.ItemId,
 			Quantity:  oi.Quantity,
 			ItemPrice: (item.Price * int64(oi.Quantity)),
+			TaxClass:  item.TaxClass,
 			CreatedAt: createdAt,
 			UpdatedAt: updatedAt,
 		})
 
-		dataOrder.TotalAmount += (item.Price * int64(oi.Quantity))
 		dataOrder.TotalQuantity += oi.Quantity
 
 		i++
@@ -237,6 +274,12 @@ func (s *OrderService) UpdateOrder(ctx context.Context, order models.UpdateOrder
 
 	dataOrder.OrderItem = orderItem
 
+	// total amount include the tax of every item
+	err = s.TaxService.ApplyOrderTax(ctx, &dataOrder)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1030, nil)
+	}
+
 	err = s.OrderRepository.UpdateOrder(ctx, dataOrder)
 	if err != nil {
 		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
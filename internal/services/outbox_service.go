@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/utils/rabbitmq"
+
+	"github.com/sirupsen/logrus"
+)
+
+// outboxMaxBackoff longest wait between two publish attempt of a message
+const outboxMaxBackoff = time.Hour
+
+type IOutboxService interface {
+	RelayOutbox(ctx context.Context) (int, error)
+}
+
+type OutboxService struct {
+	OutboxRepository repositories.IOutboxRepository
+}
+
+func NewOutboxService(repository repositories.IOutboxRepository) IOutboxService {
+	return &OutboxService{
+		OutboxRepository: repository,
+	}
+}
+
+// RelayOutbox publish one batch of due outbox message and return the number of claimed message.
+// A message is published once the broker confirm it. Failed publish is retried with exponential backoff
+// until OUTBOX_MAX_ATTEMPT, an unroutable message is failed at once. A message published
+// but not marked is published again after the claim lease, so consumer must tolerate duplicate
+func (s *OutboxService) RelayOutbox(ctx context.Context) (int, error) {
+	cfg := config.Get().Outbox
+
+	message, err := s.OutboxRepository.ClaimOutbox(ctx, cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range message {
+		m.Attempts++
+		currentTime := time.Now()
+
+		err = s.OutboxRepository.PublishOutbox(ctx, m)
+		if err == nil {
+			m.Status = helper.OutboxPublished
+			m.PublishedAt = &currentTime
+
+			err = s.OutboxRepository.MarkOutboxPublished(ctx, m)
+			if err != nil {
+				logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			}
+			continue
+		}
+
+		m.LastError = err.Error()
+
+		// an unroutable message is returned again on every attempt until the topology is fixed
+		if m.Attempts >= cfg.MaxAttempt || rabbitmq.IsUnroutable(err) {
+			m.Status = helper.OutboxFailed
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Errorf("outbox message %s to %s failed after %d attempt", m.Id, m.Topic, m.Attempts)
+		}
+
+		nextAttempt := currentTime.Add(outboxBackoff(time.Duration(cfg.RetryBackoff)*time.Second, m.Attempts))
+		m.NextAttemptAt = &nextAttempt
+
+		err = s.OutboxRepository.MarkOutboxFailed(ctx, m)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+	}
+
+	return len(message), nil
+}
+
+// outboxBackoff double the wait after every failed attempt
+func outboxBackoff(backoff time.Duration, attempts int) time.Duration {
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > outboxMaxBackoff {
+		return outboxMaxBackoff
+	}
+
+	return backoff
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/galihfebrizki/dbo-api/internal/repositories"
 	"github.com/galihfebrizki/dbo-api/internal/responses"
 	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
+	"github.com/galihfebrizki/dbo-api/utils/payment"
+	utils "github.com/galihfebrizki/dbo-api/utils/snowflake"
 
 	"github.com/sirupsen/logrus"
 )
@@ -18,17 +21,21 @@ import (
 type IPaymentService interface {
 	PaymentProccessSend(ctx context.Context, orderId string) (int, responses.GenericResponse)
 	PaymentProccessReceived(ctx context.Context, orderData models.Order) error
+	PaymentWebhookReceived(ctx context.Context, provider string, webhook models.PaymentWebhook, payload []byte) (int, responses.GenericResponse)
+	PaymentReconciled(ctx context.Context, attempt models.PaymentAttempt, result payment.ChargeResult) error
 }
 
 type PaymentService struct {
 	PaymentRepository repositories.IPaymentRepository
 	OrderRepository   repositories.IOrderRepository
+	PaymentProvider   payment.IRegistry
 }
 
-func NewPaymentService(repository repositories.IPaymentRepository, orderRepository repositories.IOrderRepository) IPaymentService {
+func NewPaymentService(repository repositories.IPaymentRepository, orderRepository repositories.IOrderRepository, paymentProvider payment.IRegistry) IPaymentService {
 	return &PaymentService{
 		PaymentRepository: repository,
 		OrderRepository:   orderRepository,
+		PaymentProvider:   paymentProvider,
 	}
 }
 
@@ -58,28 +65,23 @@ func (s *PaymentService) PaymentProccessSend(ctx context.Context, orderId string
 		return http.StatusOK, *responses.NewGenericResponse(1012, nil)
 	}
 
-	err = s.OrderRepository.UpdateStatusOrder(ctx, helper.StatusReadyToPay, orderId)
-	if err != nil {
-		return http.StatusOK, *responses.NewGenericResponse(1, nil)
-	}
-
 	currentTime := time.Now()
 
-	err = s.OrderRepository.InsertLog(ctx, models.OrderLog{
+	order.Status = helper.StatusReadyToPay
+	order.UpdatedAt = &currentTime
+
+	// status and payment message are committed together, the outbox relay publish the message
+	err = s.PaymentRepository.SendOrderToPayment(ctx, order, models.OrderLog{
 		OrderId:     orderId,
 		OrderStatus: helper.StatusReadyToPay,
 		CreatedAt:   &currentTime,
 		UpdatedAt:   &currentTime,
 	})
 	if err != nil {
-		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
-	}
-
-	order.Status = helper.StatusReadyToPay
-
-	err = s.PaymentRepository.PublishOrderToPayment(ctx, order)
-	if err != nil {
-		return http.StatusInternalServerError, *responses.NewGenericResponse(-1018, nil)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(1012, nil)
+		}
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1008, nil)
 	}
 
 	return http.StatusOK, *responses.NewGenericResponse(0, order)
@@ -100,29 +102,226 @@ func (s *PaymentService) PaymentProccessReceived(ctx context.Context, orderData
 		return nil
 	}
 
-	if order.Status != helper.StatusReadyToPay {
+	// order already finished, the message is a duplicate
+	if isSettled(order.Status) {
 		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(responses.GetErrorCodeEN(1012))
+		return nil
+	}
+
+	provider, err := s.PaymentProvider.Get(order.PaymentMethod)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Errorf("payment method %s: %s", order.PaymentMethod, err)
+		return s.failOrder(ctx, order.Id)
 	}
 
-	// call 3rd party payment
+	attempt, err := s.pendingAttempt(ctx, order, provider.Name())
+	if err != nil {
+		return err
+	}
+
+	var result payment.ChargeResult
 
-	err = s.OrderRepository.UpdateStatusOrder(ctx, helper.StatusPaid, order.Id)
+	// a redelivery of the message query the charge already created instead of charging again,
+	// the status is queried again on the next delivery when the provider is not reachable
+	if attempt.Reference != "" {
+		result, err = provider.QueryStatus(ctx, attempt.Reference)
+		if err != nil {
+			return err
+		}
+	} else {
+		result, err = provider.CreateCharge(ctx, payment.ChargeRequest{
+			IdempotencyKey: attempt.Id,
+			OrderId:        order.Id,
+			PaymentMethod:  order.PaymentMethod,
+			Amount:         order.TotalAmount,
+		})
+	}
 	if err != nil {
 		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
-		return nil
+		result = payment.ChargeResult{Reference: attempt.Reference, Status: payment.StatusFailed, FailureReason: err.Error()}
+	}
+
+	// still pending charge is settled by the webhook or the reconciliation
+	return s.applyChargeResult(ctx, attempt, result)
+}
+
+// pendingAttempt the pending attempt of the order, a new attempt is created only when the order has none,
+// so every delivery of the payment message use the same idempotency key
+func (s *PaymentService) pendingAttempt(ctx context.Context, order models.Order, provider string) (models.PaymentAttempt, error) {
+	attempt, err := s.PaymentRepository.GetLatestPaymentAttempt(ctx, order.Id)
+	if err == nil && attempt.Status == payment.StatusPending && attempt.Provider == provider {
+		return attempt, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.PaymentAttempt{}, err
 	}
 
 	currentTime := time.Now()
 
-	err = s.OrderRepository.InsertLog(ctx, models.OrderLog{
-		OrderId:     order.Id,
-		OrderStatus: helper.StatusPaid,
-		CreatedAt:   &currentTime,
-		UpdatedAt:   &currentTime,
+	attempt = models.PaymentAttempt{
+		Id:            utils.GenerateSnowflakePayment(),
+		OrderId:       order.Id,
+		Provider:      provider,
+		PaymentMethod: order.PaymentMethod,
+		Amount:        order.TotalAmount,
+		Status:        payment.StatusPending,
+		CreatedAt:     &currentTime,
+	}
+
+	err = s.PaymentRepository.CreatePaymentAttempt(ctx, attempt)
+	if err != nil {
+		return models.PaymentAttempt{}, err
+	}
+
+	return attempt, nil
+}
+
+// PaymentWebhookReceived apply the charge status sent by provider, every event id is only applied once
+func (s *PaymentService) PaymentWebhookReceived(ctx context.Context, provider string, webhook models.PaymentWebhook, payload []byte) (int, responses.GenericResponse) {
+	status, ok := payment.MapStatus(webhook.Status)
+	if !ok {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Errorf("unknown payment status %s", webhook.Status)
+		return http.StatusBadRequest, *responses.NewGenericResponse(1003, nil)
+	}
+
+	currentTime := time.Now()
+
+	isNew, err := s.PaymentRepository.CreateWebhookEvent(ctx, models.PaymentWebhookEvent{
+		Provider:   provider,
+		EventId:    webhook.EventId,
+		OrderId:    webhook.OrderId,
+		Status:     webhook.Status,
+		Payload:    string(payload),
+		ReceivedAt: &currentTime,
 	})
+	if err != nil {
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1008, nil)
+	}
+
+	// duplicate delivery, provider only need the acknowledgement
+	if !isNew {
+		return http.StatusOK, *responses.NewGenericResponse(0, nil)
+	}
+
+	// the event is claimed until it is processed, an event that is not processed is released so the next
+	// delivery of the provider process it
+	processed := false
+	defer func() {
+		if !processed {
+			s.PaymentRepository.ReleaseWebhookEvent(lifecycle.WithoutCancel(ctx), provider, webhook.EventId)
+		}
+	}()
+
+	attempt, err := s.PaymentRepository.GetPaymentAttemptByReference(ctx, provider, webhook.Reference)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		attempt, err = s.PaymentRepository.GetLatestPaymentAttempt(ctx, webhook.OrderId)
+	}
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusNotFound, *responses.NewGenericResponse(1024, nil)
+		}
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1008, nil)
+	}
+
+	if attempt.OrderId != webhook.OrderId || attempt.Provider != provider {
+		return http.StatusNotFound, *responses.NewGenericResponse(1024, nil)
+	}
+
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, attempt.OrderId)
+	if err != nil {
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1008, nil)
+	}
+
+	switch {
+	case isSettled(order.Status):
+		// order already has its final payment status
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("order %s already settled, webhook event %s ignored", order.Id, webhook.EventId)
+	case status == payment.StatusSuccess && webhook.Amount != attempt.Amount:
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Errorf("order %s paid %d, expected %d", order.Id, webhook.Amount, attempt.Amount)
+		return http.StatusOK, *responses.NewGenericResponse(1025, nil)
+	default:
+		reference := webhook.Reference
+		if reference == "" {
+			reference = attempt.Reference
+		}
+
+		err = s.applyChargeResult(ctx, attempt, payment.ChargeResult{
+			Reference:     reference,
+			Status:        status,
+			FailureReason: webhook.FailureReason,
+			PaidAt:        webhook.PaidAt,
+		})
+		if err != nil {
+			return http.StatusInternalServerError, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	err = s.PaymentRepository.UpdateWebhookEventProcessed(ctx, provider, webhook.EventId)
 	if err != nil {
 		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
 	}
+	processed = err == nil
+
+	return http.StatusOK, *responses.NewGenericResponse(0, nil)
+}
+
+// PaymentReconciled apply the charge status found by reconciliation, order that already settled is left untouched
+func (s *PaymentService) PaymentReconciled(ctx context.Context, attempt models.PaymentAttempt, result payment.ChargeResult) error {
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, attempt.OrderId)
+	if err != nil {
+		return err
+	}
+
+	if order.Status != helper.StatusReadyToPay {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("order %s already settled, reconciliation ignored", order.Id)
+		return nil
+	}
+
+	return s.applyChargeResult(ctx, attempt, result)
+}
+
+// applyChargeResult store the provider result on the attempt and move the order to paid or failed
+func (s *PaymentService) applyChargeResult(ctx context.Context, attempt models.PaymentAttempt, result payment.ChargeResult) error {
+	currentTime := time.Now()
+
+	attempt.Status = result.Status
+	attempt.Reference = result.Reference
+	attempt.FailureReason = result.FailureReason
+	attempt.PaidAt = result.PaidAt
+	attempt.UpdatedAt = &currentTime
+
+	// the error is returned so the payment message or the webhook is delivered again
+	err := s.PaymentRepository.UpdatePaymentAttempt(ctx, attempt)
+	if err != nil {
+		return err
+	}
+
+	switch result.Status {
+	case payment.StatusFailed:
+		return s.failOrder(ctx, attempt.OrderId)
+	case payment.StatusPending:
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("payment of order %s is still pending", attempt.OrderId)
+		return nil
+	}
+
+	paymentDate := result.PaidAt
+	if paymentDate == nil {
+		paymentDate = &currentTime
+	}
+
+	return s.OrderRepository.UpdatePaymentOrder(ctx, attempt.OrderId, result.Reference, paymentDate)
+}
+
+func (s *PaymentService) failOrder(ctx context.Context, orderId string) error {
+	return s.OrderRepository.FailPaymentOrder(ctx, orderId)
+}
+
+// isSettled the order has its final payment status, a refunded order was paid before
+func isSettled(status int) bool {
+	switch status {
+	case helper.StatusPaid, helper.StatusSuccess, helper.StatusFailed, helper.StatusRefunded, helper.StatusPartiallyRefunded:
+		return true
+	}
 
-	return nil
+	return false
 }
@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/utils/export"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/payment"
+
+	"github.com/sirupsen/logrus"
+)
+
+var reconciliationHeader = []interface{}{
+	"order_id", "order_status", "order_amount", "payment_acquirement_id", "provider", "reference",
+	"provider_status", "provider_amount", "discrepancy", "action",
+}
+
+// reconciliationStatus order status that has been sent to the provider
+var reconciliationStatus = []int{
+	helper.StatusReadyToPay, helper.StatusPaid, helper.StatusSuccess,
+	helper.StatusRefunded, helper.StatusPartiallyRefunded, helper.StatusFailed,
+}
+
+type IReconciliationService interface {
+	Reconcile(ctx context.Context, filter models.ReconciliationFilter, settlement []payment.Settlement, w io.Writer) (models.ReconciliationSummary, error)
+}
+
+type ReconciliationService struct {
+	OrderRepository   repositories.IOrderRepository
+	PaymentRepository repositories.IPaymentRepository
+	PaymentService    IPaymentService
+	PaymentProvider   payment.IRegistry
+	RefundRepository  repositories.IRefundRepository
+	RefundService     IRefundService
+}
+
+func NewReconciliationService(orderRepository repositories.IOrderRepository, paymentRepository repositories.IPaymentRepository, paymentService IPaymentService, paymentProvider payment.IRegistry, refundRepository repositories.IRefundRepository, refundService IRefundService) IReconciliationService {
+	return &ReconciliationService{
+		OrderRepository:   orderRepository,
+		PaymentRepository: paymentRepository,
+		PaymentService:    paymentService,
+		PaymentProvider:   paymentProvider,
+		RefundRepository:  refundRepository,
+		RefundService:     refundService,
+	}
+}
+
+// Reconcile compare order payment with the provider settlement report, without settlement report every charge
+// status is queried from its provider. A refund pending for longer than the stuck duration is reported too.
+// Every discrepancy is written into w as csv
+func (s *ReconciliationService) Reconcile(ctx context.Context, filter models.ReconciliationFilter, settlement []payment.Settlement, w io.Writer) (models.ReconciliationSummary, error) {
+	summary := models.ReconciliationSummary{}
+
+	writer, err := export.NewWriter(export.FormatCSV, w)
+	if err != nil {
+		return summary, err
+	}
+
+	err = writer.Write(reconciliationHeader)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return summary, err
+	}
+
+	order, err := s.OrderRepository.GetOrderByStatus(ctx, reconciliationStatus, filter.DateFrom, filter.DateTo)
+	if err != nil {
+		return summary, err
+	}
+
+	var settled map[string]payment.Settlement
+	if settlement != nil {
+		settled = make(map[string]payment.Settlement, len(settlement))
+		for _, row := range settlement {
+			settled[row.Reference] = row
+		}
+	}
+
+	for _, o := range order {
+		summary.Checked++
+
+		discrepancy, err := s.reconcileOrder(ctx, filter, o, settled)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Errorf("reconcile order %s: %s", o.Id, err)
+			continue
+		}
+
+		if discrepancy == nil {
+			continue
+		}
+
+		err = s.writeDiscrepancy(writer, &summary, *discrepancy)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return summary, err
+		}
+	}
+
+	err = s.reconcileRefund(ctx, filter, writer, &summary)
+	if err != nil {
+		return summary, err
+	}
+
+	// settlement row left are not matched with any order of the date range
+	for _, row := range settlement {
+		if _, ok := settled[row.Reference]; !ok {
+			continue
+		}
+
+		_, err := s.PaymentRepository.GetPaymentAttemptByReference(ctx, filter.Provider, row.Reference)
+		if err == nil {
+			// charge of order outside the date range
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return summary, err
+		}
+
+		err = s.writeDiscrepancy(writer, &summary, models.ReconciliationDiscrepancy{
+			OrderId:        row.OrderId,
+			Provider:       filter.Provider,
+			Reference:      row.Reference,
+			ProviderStatus: row.Status,
+			ProviderAmount: row.Amount,
+			Discrepancy:    helper.DiscrepancyUnknownSettlement,
+		})
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return summary, err
+		}
+	}
+
+	return summary, writer.Close()
+}
+
+// reconcileOrder return nil when order payment match the provider
+func (s *ReconciliationService) reconcileOrder(ctx context.Context, filter models.ReconciliationFilter, order models.Order, settled map[string]payment.Settlement) (*models.ReconciliationDiscrepancy, error) {
+	attempt, err := s.PaymentRepository.GetLatestPaymentAttempt(ctx, order.Id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	hasAttempt := err == nil
+
+	discrepancy := models.ReconciliationDiscrepancy{
+		OrderId:              order.Id,
+		OrderStatus:          order.Status,
+		OrderAmount:          order.TotalAmount,
+		PaymentAcquirementId: order.PaymentAcquirementId,
+		Provider:             attempt.Provider,
+		Reference:            order.PaymentAcquirementId,
+	}
+
+	if discrepancy.Reference == "" {
+		discrepancy.Reference = attempt.Reference
+	}
+
+	// charge never reached the provider, the payment message is lost
+	if discrepancy.Reference == "" {
+		statusAt := order.UpdatedAt
+		if statusAt == nil {
+			statusAt = order.CreatedAt
+		}
+
+		if order.Status != helper.StatusReadyToPay || !isStuck(statusAt, filter.StuckAfter) {
+			return nil, nil
+		}
+
+		discrepancy.Discrepancy = helper.DiscrepancyStuckOrder
+
+		if filter.Redrive {
+			err = s.PaymentRepository.PublishOrderToPayment(ctx, order)
+			if err == nil {
+				discrepancy.Action = helper.ReconcileRepublished
+			}
+		}
+
+		return &discrepancy, nil
+	}
+
+	// order paid before payment attempt exist, provider is selected from its payment method
+	if !hasAttempt {
+		provider, err := s.PaymentProvider.Get(order.PaymentMethod)
+		if err != nil {
+			return nil, err
+		}
+		discrepancy.Provider = provider.Name()
+	}
+
+	var (
+		result payment.ChargeResult
+		found  bool
+	)
+
+	if settled != nil {
+		if discrepancy.Provider != filter.Provider {
+			return nil, nil
+		}
+
+		row, ok := settled[discrepancy.Reference]
+		if ok {
+			delete(settled, discrepancy.Reference)
+			found = true
+			result = payment.ChargeResult{
+				Reference: row.Reference,
+				Status:    row.Status,
+				Amount:    row.Amount,
+				PaidAt:    row.SettledAt,
+			}
+		}
+	} else {
+		provider, err := s.PaymentProvider.Provider(discrepancy.Provider)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err = provider.QueryStatus(ctx, discrepancy.Reference)
+		if err != nil && !errors.Is(err, payment.ErrChargeNotFound) {
+			return nil, err
+		}
+		found = err == nil
+	}
+
+	discrepancy.ProviderStatus = result.Status
+	discrepancy.ProviderAmount = result.Amount
+
+	isPaid := order.Status == helper.StatusPaid || order.Status == helper.StatusSuccess ||
+		order.Status == helper.StatusRefunded || order.Status == helper.StatusPartiallyRefunded
+
+	switch {
+	case isPaid && (!found || result.Status != payment.StatusSuccess):
+		discrepancy.Discrepancy = helper.DiscrepancyNotSettled
+	case !found:
+		// pending charge is not in the settlement report yet
+		if order.Status != helper.StatusReadyToPay || !isStuck(attempt.CreatedAt, filter.StuckAfter) {
+			return nil, nil
+		}
+		discrepancy.Discrepancy = helper.DiscrepancyPendingPayment
+	case result.Status == payment.StatusSuccess && result.Amount != 0 && result.Amount != order.TotalAmount:
+		discrepancy.Discrepancy = helper.DiscrepancyAmountMismatch
+	case isPaid:
+		return nil, nil
+	case order.Status == helper.StatusFailed && result.Status == payment.StatusSuccess:
+		// customer is charged for a failed order, must be refunded manually
+		discrepancy.Discrepancy = helper.DiscrepancySettledFailed
+	case order.Status != helper.StatusReadyToPay:
+		return nil, nil
+	case result.Status == payment.StatusSuccess:
+		discrepancy.Discrepancy = helper.DiscrepancySettledNotPaid
+		if filter.Redrive && hasAttempt {
+			s.redrive(ctx, &discrepancy, attempt, result, helper.ReconcileMarkedPaid)
+		}
+	case result.Status == payment.StatusFailed:
+		discrepancy.Discrepancy = helper.DiscrepancyFailedNotRecorded
+		if filter.Redrive && hasAttempt {
+			s.redrive(ctx, &discrepancy, attempt, result, helper.ReconcileMarkedFailed)
+		}
+	default:
+		if !isStuck(attempt.CreatedAt, filter.StuckAfter) {
+			return nil, nil
+		}
+		discrepancy.Discrepancy = helper.DiscrepancyPendingPayment
+	}
+
+	return &discrepancy, nil
+}
+
+// reconcileRefund report the refund stuck in pending, with redrive the refund is sent again to its provider with
+// the same idempotency key and its result is applied
+func (s *ReconciliationService) reconcileRefund(ctx context.Context, filter models.ReconciliationFilter, writer export.IWriter, summary *models.ReconciliationSummary) error {
+	refund, err := s.RefundRepository.GetPendingRefund(ctx, filter.DateFrom, filter.DateTo)
+	if err != nil {
+		return err
+	}
+
+	for _, rf := range refund {
+		summary.Checked++
+
+		if !isStuck(rf.CreatedAt, filter.StuckAfter) {
+			continue
+		}
+
+		discrepancy := models.ReconciliationDiscrepancy{
+			OrderId:        rf.OrderId,
+			Reference:      rf.Id,
+			ProviderStatus: payment.StatusPending,
+			ProviderAmount: rf.Amount,
+			Discrepancy:    helper.DiscrepancyPendingRefund,
+		}
+
+		if filter.Redrive {
+			result, err := s.RefundService.RecheckRefund(ctx, rf)
+			if err != nil {
+				logrus.WithField(helper.GetRequestIDContext(ctx)).Errorf("reconcile refund %s: %s", rf.Id, err)
+				continue
+			}
+			discrepancy.ProviderStatus = result.Status
+
+			err = s.RefundService.RefundReconciled(ctx, rf, result)
+			switch {
+			case err != nil:
+				logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			case result.Status == payment.StatusSuccess:
+				discrepancy.Action = helper.ReconcileRefunded
+			case result.Status == payment.StatusFailed:
+				discrepancy.Action = helper.ReconcileRefundFailed
+			}
+		}
+
+		err = s.writeDiscrepancy(writer, summary, discrepancy)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// redrive apply the provider result to the order, action is only recorded when it succeed
+func (s *ReconciliationService) redrive(ctx context.Context, discrepancy *models.ReconciliationDiscrepancy, attempt models.PaymentAttempt, result payment.ChargeResult, action string) {
+	if result.Reference == "" {
+		result.Reference = discrepancy.Reference
+	}
+
+	err := s.PaymentService.PaymentReconciled(ctx, attempt, result)
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return
+	}
+
+	discrepancy.Action = action
+}
+
+func (s *ReconciliationService) writeDiscrepancy(writer export.IWriter, summary *models.ReconciliationSummary, discrepancy models.ReconciliationDiscrepancy) error {
+	summary.Discrepancy++
+	if discrepancy.Action != "" {
+		summary.Redriven++
+	}
+
+	return writer.Write([]interface{}{
+		discrepancy.OrderId,
+		discrepancy.OrderStatus,
+		discrepancy.OrderAmount,
+		discrepancy.PaymentAcquirementId,
+		discrepancy.Provider,
+		discrepancy.Reference,
+		discrepancy.ProviderStatus,
+		discrepancy.ProviderAmount,
+		discrepancy.Discrepancy,
+		discrepancy.Action,
+	})
+}
+
+// isStuck no payment progress since the given time, unknown time is treated as stuck
+func isStuck(since *time.Time, after time.Duration) bool {
+	if since == nil {
+		return true
+	}
+
+	return time.Since(*since) >= after
+}
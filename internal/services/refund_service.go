@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/payment"
+	utils "github.com/galihfebrizki/dbo-api/utils/snowflake"
+
+	"github.com/sirupsen/logrus"
+)
+
+type IRefundService interface {
+	GetRefund(ctx context.Context, orderId string) (int, responses.GenericResponse)
+	RefundProccessSend(ctx context.Context, request models.RefundOrder, userId string) (int, responses.GenericResponse)
+	RefundProccessReceived(ctx context.Context, refundData models.Refund) error
+	RecheckRefund(ctx context.Context, refund models.Refund) (payment.RefundResult, error)
+	RefundReconciled(ctx context.Context, refund models.Refund, result payment.RefundResult) error
+}
+
+type RefundService struct {
+	RefundRepository  repositories.IRefundRepository
+	OrderRepository   repositories.IOrderRepository
+	PaymentRepository repositories.IPaymentRepository
+	PaymentProvider   payment.IRegistry
+}
+
+func NewRefundService(repository repositories.IRefundRepository, orderRepository repositories.IOrderRepository, paymentRepository repositories.IPaymentRepository, paymentProvider payment.IRegistry) IRefundService {
+	return &RefundService{
+		RefundRepository:  repository,
+		OrderRepository:   orderRepository,
+		PaymentRepository: paymentRepository,
+		PaymentProvider:   paymentProvider,
+	}
+}
+
+func (s *RefundService) GetRefund(ctx context.Context, orderId string) (int, responses.GenericResponse) {
+	refund, err := s.RefundRepository.GetRefundByOrderId(ctx, orderId)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	for i := range refund {
+		refund[i].RefundItem, err = s.RefundRepository.GetRefundItemByRefundId(ctx, refund[i].Id)
+		if err != nil {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, refund)
+}
+
+func (s *RefundService) RefundProccessSend(ctx context.Context, request models.RefundOrder, userId string) (int, responses.GenericResponse) {
+	if request.Amount != 0 && len(request.RefundItem) > 0 {
+		return http.StatusBadRequest, *responses.NewGenericResponse(1003, nil)
+	}
+
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, request.OrderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(-1018, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	if order.Status != helper.StatusPaid && order.Status != helper.StatusSuccess && order.Status != helper.StatusPartiallyRefunded {
+		return http.StatusOK, *responses.NewGenericResponse(1026, nil)
+	}
+
+	attempt, err := s.PaymentRepository.GetLatestPaymentAttempt(ctx, order.Id)
+	if err != nil || attempt.Status != payment.StatusSuccess {
+		return http.StatusOK, *responses.NewGenericResponse(1026, nil)
+	}
+
+	previousRefund, err := s.RefundRepository.GetRefundByOrderId(ctx, order.Id)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	// one refund at a time, the remaining amount depends on the previous refund result
+	refundedAmount := int64(0)
+	for _, rf := range previousRefund {
+		switch rf.Status {
+		case helper.RefundPending:
+			return http.StatusOK, *responses.NewGenericResponse(1027, nil)
+		case helper.RefundSuccess:
+			refundedAmount += rf.Amount
+		}
+	}
+
+	remainingAmount := order.TotalAmount - refundedAmount
+
+	currentTime := time.Now()
+
+	refund := models.Refund{
+		Id:               utils.GenerateSnowflakePayment(),
+		OrderId:          order.Id,
+		PaymentAttemptId: attempt.Id,
+		Amount:           request.Amount,
+		Reason:           request.Reason,
+		Status:           helper.RefundPending,
+		RequestedBy:      userId,
+		CreatedAt:        &currentTime,
+	}
+
+	// amount only refund does not return any item
+	if request.Amount == 0 {
+		refund.RefundItem, err = s.refundItem(ctx, order.Id, refund.Id, request, &currentTime)
+		if err != nil {
+			return http.StatusOK, *responses.NewGenericResponse(1028, nil)
+		}
+
+		for _, ri := range refund.RefundItem {
+			refund.Amount += ri.Amount
+		}
+
+		// order discount is not spread to the lines, refund can not exceed what was paid
+		if len(request.RefundItem) == 0 || refund.Amount > remainingAmount {
+			refund.Amount = remainingAmount
+		}
+	}
+
+	if refund.Amount <= 0 || refund.Amount > remainingAmount {
+		return http.StatusOK, *responses.NewGenericResponse(1028, nil)
+	}
+
+	err = s.RefundRepository.CreateRefund(ctx, refund)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrRefundPending):
+			return http.StatusOK, *responses.NewGenericResponse(1027, nil)
+		case errors.Is(err, repositories.ErrRefundAmount):
+			return http.StatusOK, *responses.NewGenericResponse(1028, nil)
+		}
+		return http.StatusInternalServerError, *responses.NewGenericResponse(1008, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, refund)
+}
+
+// refundItem build the refunded line of the order, without requested item every line not refunded yet is returned.
+// Line amount is the paid line total (after discount, with exclusive tax) split by quantity,
+// the last unit of a line take the rounding remainder
+func (s *RefundService) refundItem(ctx context.Context, orderId string, refundId string, request models.RefundOrder, currentTime *time.Time) ([]models.RefundItem, error) {
+	orderItem, err := s.OrderRepository.GetOrderItemByOrderId(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	refundedItem, err := s.RefundRepository.GetRefundItemByOrderId(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	refundedQuantity := map[string]int{}
+	refundedAmount := map[string]int64{}
+	for _, ri := range refundedItem {
+		refundedQuantity[ri.OrderItemId] += ri.Quantity
+		refundedAmount[ri.OrderItemId] += ri.Amount
+	}
+
+	requested := map[string]int{}
+	for _, ri := range request.RefundItem {
+		requested[ri.OrderItemId] += ri.Quantity
+	}
+
+	refundItem := []models.RefundItem{}
+
+	for _, oi := range orderItem {
+		remainingQuantity := oi.Quantity - refundedQuantity[oi.Id]
+
+		quantity := remainingQuantity
+		if len(request.RefundItem) > 0 {
+			quantity = requested[oi.Id]
+			delete(requested, oi.Id)
+		}
+
+		if quantity == 0 {
+			continue
+		}
+
+		if quantity < 0 || quantity > remainingQuantity {
+			return nil, errors.New(responses.GetErrorCodeEN(1028))
+		}
+
+		lineAmount := oi.ItemPrice - oi.DiscountAmount
+		if !oi.PriceInclusive {
+			lineAmount += oi.TaxAmount
+		}
+
+		amount := lineAmount * int64(quantity) / int64(oi.Quantity)
+		if quantity == remainingQuantity {
+			amount = lineAmount - refundedAmount[oi.Id]
+		}
+
+		refundItem = append(refundItem, models.RefundItem{
+			RefundId:    refundId,
+			OrderItemId: oi.Id,
+			ItemId:      oi.ItemId,
+			Quantity:    quantity,
+			Amount:      amount,
+			CreatedAt:   currentTime,
+		})
+	}
+
+	// requested item that is not part of the order
+	if len(requested) > 0 {
+		return nil, errors.New(responses.GetErrorCodeEN(1028))
+	}
+
+	return refundItem, nil
+}
+
+func (s *RefundService) RefundProccessReceived(ctx context.Context, refundData models.Refund) error {
+	if refundData.Id == "" {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("refund not valid refundId : %s", refundData.Id)
+		return nil
+	}
+
+	refund, err := s.RefundRepository.GetRefundById(ctx, refundData.Id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// refund already processed, the message is a duplicate
+	if refund.Status != helper.RefundPending {
+		return nil
+	}
+
+	refund.RefundItem, err = s.RefundRepository.GetRefundItemByRefundId(ctx, refund.Id)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.RecheckRefund(ctx, refund)
+	if err != nil {
+		return err
+	}
+
+	if result.Status == payment.StatusPending {
+		// the refund stay pending until the reconciliation find its final status on the provider
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Infof("refund %s is still pending on provider", refund.Id)
+		return nil
+	}
+
+	return s.applyRefundResult(ctx, refund, result)
+}
+
+// RecheckRefund send the refund to its provider, the provider return the current result of a refund already sent
+// with the same idempotency key. A provider error is a failed refund
+func (s *RefundService) RecheckRefund(ctx context.Context, refund models.Refund) (payment.RefundResult, error) {
+	attempt, err := s.PaymentRepository.GetPaymentAttemptById(ctx, refund.PaymentAttemptId)
+	if err != nil {
+		return payment.RefundResult{}, err
+	}
+
+	result := payment.RefundResult{Status: payment.StatusFailed}
+
+	provider, err := s.PaymentProvider.Provider(attempt.Provider)
+	if err == nil {
+		result, err = provider.Refund(ctx, payment.RefundRequest{
+			IdempotencyKey: refund.Id,
+			Reference:      attempt.Reference,
+			Amount:         refund.Amount,
+			Reason:         refund.Reason,
+		})
+	}
+	if err != nil {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		result = payment.RefundResult{Status: payment.StatusFailed, FailureReason: err.Error()}
+	}
+
+	return result, nil
+}
+
+// RefundReconciled apply the refund status found by the reconciliation, a refund that is not pending anymore
+// is left untouched
+func (s *RefundService) RefundReconciled(ctx context.Context, refund models.Refund, result payment.RefundResult) error {
+	if refund.Status != helper.RefundPending || result.Status == payment.StatusPending {
+		return nil
+	}
+
+	var err error
+
+	refund.RefundItem, err = s.RefundRepository.GetRefundItemByRefundId(ctx, refund.Id)
+	if err != nil {
+		return err
+	}
+
+	return s.applyRefundResult(ctx, refund, result)
+}
+
+// applyRefundResult fail the refund, or complete it and move the order to partially refunded or refunded
+func (s *RefundService) applyRefundResult(ctx context.Context, refund models.Refund, result payment.RefundResult) error {
+	refund.ProviderReference = result.Reference
+	refund.FailureReason = result.FailureReason
+
+	if result.Status == payment.StatusFailed {
+		return s.RefundRepository.FailRefund(ctx, refund)
+	}
+
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, refund.OrderId)
+	if err != nil {
+		return err
+	}
+
+	previousRefund, err := s.RefundRepository.GetRefundByOrderId(ctx, refund.OrderId)
+	if err != nil {
+		return err
+	}
+
+	refundedAmount := refund.Amount
+	for _, rf := range previousRefund {
+		if rf.Status == helper.RefundSuccess {
+			refundedAmount += rf.Amount
+		}
+	}
+
+	orderStatus := helper.StatusPartiallyRefunded
+	if refundedAmount >= order.TotalAmount {
+		orderStatus = helper.StatusRefunded
+	}
+
+	currentTime := time.Now()
+
+	refund.Status = helper.RefundSuccess
+	refund.UpdatedAt = &currentTime
+	refund.ProcessedAt = &currentTime
+
+	return s.RefundRepository.CompleteRefund(ctx, refund, orderStatus)
+}
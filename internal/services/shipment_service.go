@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	utils "github.com/galihfebrizki/dbo-api/utils/snowflake"
+
+	"github.com/sirupsen/logrus"
+)
+
+// shipmentTransition allowed next status of every shipment status, delivered and failed are final
+var shipmentTransition = map[int][]int{
+	helper.ShipmentCreated:   {helper.ShipmentPickedUp, helper.ShipmentFailed},
+	helper.ShipmentPickedUp:  {helper.ShipmentInTransit, helper.ShipmentDelivered, helper.ShipmentFailed},
+	helper.ShipmentInTransit: {helper.ShipmentDelivered, helper.ShipmentFailed},
+}
+
+type IShipmentService interface {
+	GetShipment(ctx context.Context, orderId string, userId string) (int, responses.GenericResponse)
+	CreateShipment(ctx context.Context, request models.CreateShipment) (int, responses.GenericResponse)
+	UpdateShipmentStatus(ctx context.Context, request models.UpdateShipmentStatus) (int, responses.GenericResponse)
+}
+
+type ShipmentService struct {
+	ShipmentRepository repositories.IShipmentRepository
+	OrderRepository    repositories.IOrderRepository
+	UserService        IUserService
+}
+
+func NewShipmentService(repository repositories.IShipmentRepository, orderRepository repositories.IOrderRepository, userService IUserService) IShipmentService {
+	return &ShipmentService{
+		ShipmentRepository: repository,
+		OrderRepository:    orderRepository,
+		UserService:        userService,
+	}
+}
+
+func (s *ShipmentService) GetShipment(ctx context.Context, orderId string, userId string) (int, responses.GenericResponse) {
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, orderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(-1018, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	isSuperUser := s.UserService.IsSuperUser(ctx, userId)
+	if !isSuperUser && order.UserId != userId {
+		logrus.WithField(helper.GetRequestIDContext(ctx)).Error("Unauthorized User")
+		return http.StatusUnauthorized, *responses.NewGenericResponse(1004, nil)
+	}
+
+	shipment, err := s.ShipmentRepository.GetShipmentByOrderId(ctx, orderId)
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, shipment)
+}
+
+func (s *ShipmentService) CreateShipment(ctx context.Context, request models.CreateShipment) (int, responses.GenericResponse) {
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, request.OrderId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(-1018, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	if order.Status != helper.StatusPaid {
+		return http.StatusOK, *responses.NewGenericResponse(1020, nil)
+	}
+
+	currentTime := time.Now()
+
+	shipment := models.Shipment{
+		Id:             utils.GenerateSnowflakeShipment(),
+		OrderId:        order.Id,
+		Courier:        request.Courier,
+		Service:        request.Service,
+		TrackingNumber: request.TrackingNumber,
+		Status:         helper.ShipmentCreated,
+		CreatedAt:      &currentTime,
+	}
+
+	err = s.ShipmentRepository.CreateShipment(ctx, shipment, models.ShipmentLog{
+		ShipmentId:     shipment.Id,
+		ShipmentStatus: shipment.Status,
+		CreatedAt:      &currentTime,
+	})
+	if err != nil {
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, shipment)
+}
+
+func (s *ShipmentService) UpdateShipmentStatus(ctx context.Context, request models.UpdateShipmentStatus) (int, responses.GenericResponse) {
+	shipment, err := s.ShipmentRepository.GetShipmentById(ctx, request.ShipmentId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusOK, *responses.NewGenericResponse(1022, nil)
+		} else {
+			return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+		}
+	}
+
+	if !isValidShipmentTransition(shipment.Status, request.Status) {
+		return http.StatusOK, *responses.NewGenericResponse(1021, nil)
+	}
+
+	currentTime := time.Now()
+	fromStatus := shipment.Status
+
+	shipment.Status = request.Status
+	shipment.UpdatedAt = &currentTime
+
+	switch request.Status {
+	case helper.ShipmentPickedUp:
+		shipment.ShippedAt = &currentTime
+	case helper.ShipmentDelivered:
+		shipment.DeliveredAt = &currentTime
+	}
+
+	err = s.ShipmentRepository.UpdateShipmentStatus(ctx, shipment, fromStatus, models.ShipmentLog{
+		ShipmentId:     shipment.Id,
+		ShipmentStatus: shipment.Status,
+		Note:           request.Note,
+		CreatedAt:      &currentTime,
+	})
+	if err != nil {
+		if errors.Is(err, repositories.ErrShipmentStatusChanged) {
+			return http.StatusOK, *responses.NewGenericResponse(1021, nil)
+		}
+		return http.StatusOK, *responses.NewGenericResponse(1008, nil)
+	}
+
+	if shipment.Status == helper.ShipmentDelivered {
+		err = s.completeOrder(ctx, shipment)
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		}
+	}
+
+	return http.StatusOK, *responses.NewGenericResponse(0, shipment)
+}
+
+// completeOrder move paid order to success once every shipment that is not failed has been delivered
+func (s *ShipmentService) completeOrder(ctx context.Context, deliveredShipment models.Shipment) error {
+	orderId := deliveredShipment.OrderId
+
+	order, err := s.OrderRepository.GetOrderByOrderId(ctx, orderId)
+	if err != nil {
+		return err
+	}
+
+	if order.Status != helper.StatusPaid {
+		return nil
+	}
+
+	shipment, err := s.ShipmentRepository.GetShipmentByOrderId(ctx, orderId)
+	if err != nil {
+		return err
+	}
+
+	delivered := 0
+	for _, sh := range shipment {
+		// replica may not have the status that was just updated
+		if sh.Id == deliveredShipment.Id {
+			sh.Status = deliveredShipment.Status
+		}
+
+		switch sh.Status {
+		case helper.ShipmentDelivered:
+			delivered++
+		case helper.ShipmentFailed:
+		default:
+			return nil
+		}
+	}
+
+	if delivered == 0 {
+		return nil
+	}
+
+	return s.OrderRepository.CompleteOrder(ctx, orderId)
+}
+
+func isValidShipmentTransition(from int, to int) bool {
+	for _, next := range shipmentTransition[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
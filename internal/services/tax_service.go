@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/internal/models"
+	"github.com/galihfebrizki/dbo-api/internal/repositories"
+	"github.com/galihfebrizki/dbo-api/utils/tax"
+
+	"github.com/sirupsen/logrus"
+)
+
+type ITaxService interface {
+	ApplyOrderTax(ctx context.Context, order *models.InsertOrder) error
+}
+
+type TaxService struct {
+	TaxRepository repositories.ITaxRepository
+	Calculator    tax.Calculator
+}
+
+func NewTaxService(repository repositories.ITaxRepository) ITaxService {
+	return &TaxService{
+		TaxRepository: repository,
+		Calculator:    tax.NewCalculator(config.Get().Tax.Rounding, config.Get().Tax.Level),
+	}
+}
+
+// ApplyOrderTax fill tax of every order item from its tax class and recompute the order totals,
+// item without tax class use the configured default class
+func (s *TaxService) ApplyOrderTax(ctx context.Context, order *models.InsertOrder) error {
+	classes, err := s.TaxRepository.GetTaxClasses(ctx)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]tax.Line, len(order.OrderItem))
+
+	for i, oi := range order.OrderItem {
+		code := oi.TaxClass
+		if code == "" {
+			code = config.Get().Tax.DefaultClass
+		}
+
+		class, ok := classes[code]
+		if !ok {
+			err = fmt.Errorf("tax class %s not found", code)
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			return err
+		}
+
+		lines[i] = tax.Line{
+			Amount: oi.ItemPrice - oi.DiscountAmount,
+			Class:  class,
+		}
+	}
+
+	result := s.Calculator.Calculate(lines)
+
+	for i := range order.OrderItem {
+		order.OrderItem[i].TaxClass = lines[i].Class.Code
+		order.OrderItem[i].TaxRate = lines[i].Class.RateBps
+		order.OrderItem[i].PriceInclusive = lines[i].Class.PriceInclusive
+		order.OrderItem[i].TaxAmount = result.Lines[i].Tax
+	}
+
+	order.TotalTaxAmount = result.Tax
+	order.TotalAmount = result.Total - order.TotalDiscountAmount
+
+	return nil
+}
@@ -0,0 +1,13 @@
+package templates
+
+import "embed"
+
+// FS hold every document template rendered by the services
+//
+//go:embed *.html *.txt
+var FS embed.FS
+
+const (
+	InvoiceHTML = "invoice.html"
+	InvoiceText = "invoice.txt"
+)
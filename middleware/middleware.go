@@ -1,12 +1,17 @@
 package middleware
 
 import (
+	"bytes"
+	"io"
 	"net/http"
+	"runtime/debug"
 	"time"
 
 	"github.com/galihfebrizki/dbo-api/config"
 	"github.com/galihfebrizki/dbo-api/helper"
 	"github.com/galihfebrizki/dbo-api/internal/responses"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+	"github.com/galihfebrizki/dbo-api/utils/payment"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
@@ -97,3 +102,75 @@ func GetToken(c *gin.Context) string {
 
 	return authHeader[len("Bearer "):]
 }
+
+// RecoveryMiddleware recover the panic of the handler with 500 like gin.Recovery, http.ErrAbortHandler is panicked
+// again without being logged so the server close the connection of a response that is already sent
+func RecoveryMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			logrus.WithField(helper.GetRequestIDContext(helper.GetGinContext(c))).Errorf("panic recovered: %v\n%s", err, debug.Stack())
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}()
+
+		c.Next()
+	}
+}
+
+// ReadAfterWriteMiddleware start the database session of the request, a read of the request after its write
+// see the write, see DB_READ_AFTER_WRITE_MODE
+func ReadAfterWriteMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(gorm.NewSession(c.Request.Context()))
+
+		c.Next()
+	}
+}
+
+// PaymentWebhookMiddleware verify X-Signature (HMAC-SHA256 of "<X-Timestamp>.<body>") sent by payment provider,
+// the body is read before the signature is verified so it is limited to PAYMENT_WEBHOOK_MAX_BODY byte
+func PaymentWebhookMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := helper.GetGinContext(c)
+		cfg := config.Get().Payment.Webhook
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, int64(cfg.MaxBody)))
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			if isBodyTooLarge(err) {
+				c.JSON(http.StatusRequestEntityTooLarge, *responses.NewGenericResponse(1031, nil))
+			} else {
+				c.JSON(http.StatusBadRequest, *responses.NewGenericResponse(1003, nil))
+			}
+			c.Abort()
+			return
+		}
+
+		// body is read again by the handler
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		err = payment.VerifySignature(cfg.Secret, c.GetHeader("X-Timestamp"), body, c.GetHeader("X-Signature"),
+			time.Duration(cfg.Tolerance)*time.Second, time.Now())
+		if err != nil {
+			logrus.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+			c.JSON(http.StatusUnauthorized, *responses.NewGenericResponse(1023, nil))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// isBodyTooLarge the error of http.MaxBytesReader, http.MaxBytesError is not available before go 1.19
+func isBodyTooLarge(err error) bool {
+	return err != nil && err.Error() == "http: request body too large"
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"time"
+)
+
+type CSVWriter struct {
+	writer *csv.Writer
+}
+
+func NewCSVWriter(w io.Writer) IWriter {
+	return &CSVWriter{
+		writer: csv.NewWriter(w),
+	}
+}
+
+func (c *CSVWriter) Write(record []interface{}) error {
+	row := make([]string, len(record))
+	for i, value := range record {
+		row[i] = formatValue(value)
+	}
+
+	return c.writer.Write(row)
+}
+
+func (c *CSVWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *CSVWriter) Close() error {
+	return c.Flush()
+}
+
+func (c *CSVWriter) ContentType() string {
+	return ContentType(FormatCSV)
+}
+
+func (c *CSVWriter) Extension() string {
+	return FormatCSV
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var (
+	// ErrUnsupportedFormat export format is not csv or xlsx
+	ErrUnsupportedFormat = errors.New("unsupported export format")
+)
+
+type IWriter interface {
+	Write(record []interface{}) error
+	Flush() error
+	Close() error
+	ContentType() string
+	Extension() string
+}
+
+// NewWriter create a streaming writer for the given format, every record is
+// written directly into w so the caller never hold the whole file in memory.
+func NewWriter(format string, w io.Writer) (IWriter, error) {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func IsValidFormat(format string) bool {
+	switch strings.ToLower(format) {
+	case FormatCSV, FormatXLSX:
+		return true
+	default:
+		return false
+	}
+}
+
+func ContentType(format string) string {
+	switch strings.ToLower(format) {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv"
+	}
+}
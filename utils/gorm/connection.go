@@ -1,9 +1,13 @@
 package gorm
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"time"
 
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
 	log "github.com/sirupsen/logrus"
 	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
@@ -116,44 +120,69 @@ func NewGormMasterConnectionPostgres(params DBParamMasterConn) IGormMaster {
 	}
 }
 
-func NewGormSlaveConnectionPostgres(params DBParamSlaveConn) IGormSlave {
+// NewGormSlaveConnectionPostgres connection of every replica balanced by the health checked pool, the read fallback
+// to the master when no replica is healthy and a read after a write of the master is routed, see readRouter
+func NewGormSlaveConnectionPostgres(params DBParamSlaveConn, master IGormMaster, redis redis.Iredis) IGormSlave {
 	var (
 		cfg = gorm.Config{
 			Logger: logger.Default.LogMode(logger.Info),
+			// an unreachable replica is ejected by the health check instead of stopping the app
+			DisableAutomaticPing: true,
 		}
 	)
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
-		params.Host,
-		params.UserName,
-		params.Password,
-		params.Name,
-		params.Port)
 
 	if !params.SQLDebug {
 		cfg.Logger = logger.Default.LogMode(logger.Silent)
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &cfg)
-	if err != nil {
-		log.Fatal(err)
+	hosts := params.Hosts
+	if len(hosts) == 0 {
+		hosts = []string{fmt.Sprintf("%s:%d", params.Host, params.Port)}
 	}
 
-	sqlDB, err := db.DB()
-	if err != nil {
-		log.Fatal(err)
-	}
+	replicas := make([]*replica, 0, len(hosts))
+	for _, address := range hosts {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			log.Fatalf("invalid replica address %s : %s", address, err)
+		}
 
-	// SetMaxIdleConns sets the maximum number of connections in the idle connection pool.
-	sqlDB.SetMaxIdleConns(params.DbConnPool)
+		dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable",
+			host,
+			params.UserName,
+			params.Password,
+			params.Name,
+			port)
 
-	// SetMaxOpenConns sets the maximum number of open connections to the database.
-	sqlDB.SetMaxOpenConns(params.DbConnPool)
+		db, err := gorm.Open(postgres.Open(dsn), &cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
 
-	// SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
-	sqlDB.SetConnMaxLifetime(time.Duration(params.DbLifeTime))
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		// SetMaxIdleConns sets the maximum number of connections in the idle connection pool.
+		sqlDB.SetMaxIdleConns(params.DbConnPool)
+
+		// SetMaxOpenConns sets the maximum number of open connections to the database.
+		sqlDB.SetMaxOpenConns(params.DbConnPool)
+
+		// SetConnMaxLifetime sets the maximum amount of time a connection may be reused.
+		sqlDB.SetConnMaxLifetime(time.Duration(params.DbLifeTime))
+
+		replicas = append(replicas, &replica{host: address, db: db})
+	}
+
+	pool := newReplicaPool(replicas, params)
+	if err := pool.check(context.Background()); err != nil {
+		log.Warn(err)
+	}
 
 	return &Gorm{
-		db:             db,
-		replicaLagTime: params.ReplicaLagTime,
+		db:     replicas[0].db,
+		router: newReadRouter(master.DB(), pool, redis, params),
 	}
 }
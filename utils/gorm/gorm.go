@@ -2,7 +2,6 @@ package gorm
 
 import (
 	"context"
-	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -48,11 +47,14 @@ type IGorm interface {
 	Ping(ctx context.Context) error
 	Close() error
 	DB() *gorm.DB
+	// Replicas state of every replica, nil on the master
+	Replicas() []ReplicaStatus
 }
 
 type Gorm struct {
-	db             *gorm.DB
-	replicaLagTime int
+	db *gorm.DB
+	// router of the replica, nil on the master
+	router *readRouter
 }
 
 type DBParamMasterConn struct {
@@ -68,21 +70,32 @@ type DBParamMasterConn struct {
 }
 
 type DBParamSlaveConn struct {
-	Host           string
-	Port           int
-	UserName       string
-	Password       string
-	Name           string
-	SQLDebug       bool
-	Extras         string
-	DbConnPool     int
-	DbLifeTime     int
-	ReplicaLagTime int
+	Host       string
+	Port       int
+	UserName   string
+	Password   string
+	Name       string
+	SQLDebug   bool
+	Extras     string
+	DbConnPool int
+	DbLifeTime int
+	// ReadAfterWriteWindow millisecond a read after a write of the same request or user is routed, 0 disable it
+	ReadAfterWriteWindow int
+	// ReadAfterWriteMode ReadAfterWriteMaster or ReadAfterWriteWaitLSN
+	ReadAfterWriteMode string
+	// ReadAfterWriteMaxWait millisecond waited for the replica in ReadAfterWriteWaitLSN mode
+	ReadAfterWriteMaxWait int
+	// Hosts host:port of every replica, Host and Port when empty
+	Hosts []string
+	// Balancer BalancerRoundRobin or BalancerLeastConn
+	Balancer string
+	// HealthCheckInterval second between the health check of the replica
+	HealthCheckInterval int
+	// MaxReplicaLag second of lag a replica is ejected above, 0 never eject a lagging replica
+	MaxReplicaLag int
 }
 
 func (g *Gorm) First(result interface{}, args ...interface{}) error {
-	time.Sleep(time.Duration(g.replicaLagTime) * time.Millisecond)
-
 	db := g.db.First(result, args...)
 	if err := db.Error; err != nil {
 		return err
@@ -92,8 +105,6 @@ func (g *Gorm) First(result interface{}, args ...interface{}) error {
 }
 
 func (g *Gorm) Find(result interface{}, args ...interface{}) error {
-	time.Sleep(time.Duration(g.replicaLagTime) * time.Millisecond)
-
 	db := g.db.Find(result, args...)
 	if err := db.Error; err != nil {
 		return err
@@ -138,8 +149,6 @@ func (g *Gorm) Update(data interface{}, onUpdate OnUpdate) error {
 }
 
 func (g *Gorm) Raw(query string, result interface{}, args ...interface{}) error {
-	time.Sleep(time.Duration(g.replicaLagTime) * time.Millisecond)
-
 	err := g.db.Raw(query, args...).Scan(result).Error
 	if err != nil {
 		return err
@@ -210,8 +219,14 @@ func (g *Gorm) Association() IGorm {
 	}
 }
 
+// WithContext on the replica read from the master after a write of the session, see readRouter
 func (g *Gorm) WithContext(ctx context.Context) IGorm {
-	db := g.db.WithContext(ctx)
+	db := g.db
+	if g.router != nil {
+		db = g.router.route(ctx)
+	}
+
+	db = db.WithContext(ctx)
 	return &Gorm{
 		db: db,
 	}
@@ -230,8 +245,12 @@ func (g *Gorm) DB() *gorm.DB {
 	return g.db
 }
 
-// Ping implements IGorm
+// Ping implements IGorm, on the replica it check every replica and fail when none is healthy
 func (g *Gorm) Ping(ctx context.Context) error {
+	if g.router != nil {
+		return g.router.pool.check(ctx)
+	}
+
 	sqlDB, err := g.db.DB()
 	if err != nil {
 		return err
@@ -247,6 +266,10 @@ func (g *Gorm) Ping(ctx context.Context) error {
 
 // Close implements IGorm
 func (g *Gorm) Close() error {
+	if g.router != nil {
+		return g.router.pool.close()
+	}
+
 	sqlDB, err := g.db.DB()
 	if err != nil {
 		return err
@@ -259,3 +282,12 @@ func (g *Gorm) Close() error {
 
 	return nil
 }
+
+// Replicas implements IGorm
+func (g *Gorm) Replicas() []ReplicaStatus {
+	if g.router == nil {
+		return nil
+	}
+
+	return g.router.pool.statuses()
+}
@@ -0,0 +1,229 @@
+package gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// replica selection of DB_READ_BALANCER
+const (
+	BalancerRoundRobin = "round_robin"
+	// BalancerLeastConn replica with the fewest connection in use
+	BalancerLeastConn = "least_conn"
+)
+
+const defaultHealthCheckInterval = 5 * time.Second
+
+// ErrNoHealthyReplica every replica is ejected, the read go to the master
+var ErrNoHealthyReplica = errors.New("no healthy replica, reading from master")
+
+// lagQuery replication lag in second, zero when the replica replayed every wal it received so an idle
+// master does not look like a lag
+const lagQuery = `SELECT CASE
+	WHEN NOT pg_is_in_recovery() OR pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+	ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+END`
+
+// ReplicaStatus state of a replica at its last health check
+type ReplicaStatus struct {
+	Host    string
+	Healthy bool
+	Lag     time.Duration
+	InUse   int
+	Error   string
+}
+
+type replica struct {
+	host    string
+	db      *gorm.DB
+	mutex   sync.RWMutex
+	healthy bool
+	lag     time.Duration
+	err     error
+}
+
+// replicaPool balance the read between the healthy replica, a replica unreachable or lagging more than
+// the max lag is ejected until a health check see it healthy again
+type replicaPool struct {
+	replicas []*replica
+	balancer string
+	maxLag   time.Duration
+	timeout  time.Duration
+	next     uint32
+	stop     chan struct{}
+	once     sync.Once
+}
+
+func newReplicaPool(replicas []*replica, params DBParamSlaveConn) *replicaPool {
+	interval := time.Duration(params.HealthCheckInterval) * time.Second
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	pool := &replicaPool{
+		replicas: replicas,
+		balancer: params.Balancer,
+		maxLag:   time.Duration(params.MaxReplicaLag) * time.Second,
+		timeout:  interval,
+		stop:     make(chan struct{}),
+	}
+
+	go pool.run(interval)
+
+	return pool
+}
+
+// pick replica of the next read, nil when no replica is healthy
+func (p *replicaPool) pick() *gorm.DB {
+	if p.balancer == BalancerLeastConn {
+		return p.leastConn()
+	}
+
+	start := int(atomic.AddUint32(&p.next, 1))
+	for i := range p.replicas {
+		r := p.replicas[(start+i)%len(p.replicas)]
+		if r.isHealthy() {
+			return r.db
+		}
+	}
+
+	return nil
+}
+
+func (p *replicaPool) leastConn() *gorm.DB {
+	var (
+		picked *gorm.DB
+		least  = -1
+	)
+
+	for _, r := range p.replicas {
+		if !r.isHealthy() {
+			continue
+		}
+
+		inUse := r.inUse()
+		if least == -1 || inUse < least {
+			picked, least = r.db, inUse
+		}
+	}
+
+	return picked
+}
+
+func (p *replicaPool) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.check(context.Background())
+		}
+	}
+}
+
+// check the health of every replica concurrently and return ErrNoHealthyReplica when none is healthy
+func (p *replicaPool) check(ctx context.Context) error {
+	wg := sync.WaitGroup{}
+	for _, r := range p.replicas {
+		wg.Add(1)
+		go func(r *replica) {
+			defer wg.Done()
+			p.checkReplica(ctx, r)
+		}(r)
+	}
+	wg.Wait()
+
+	for _, r := range p.replicas {
+		if r.isHealthy() {
+			return nil
+		}
+	}
+
+	return ErrNoHealthyReplica
+}
+
+func (p *replicaPool) checkReplica(ctx context.Context, r *replica) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var seconds float64
+	err := r.db.WithContext(ctx).Raw(lagQuery).Scan(&seconds).Error
+
+	lag := time.Duration(seconds * float64(time.Second))
+	if err == nil && p.maxLag > 0 && lag > p.maxLag {
+		err = fmt.Errorf("replication lag %s above %s", lag, p.maxLag)
+	}
+
+	r.mutex.Lock()
+	wasHealthy := r.healthy
+	r.healthy, r.lag, r.err = err == nil, lag, err
+	r.mutex.Unlock()
+
+	if wasHealthy && err != nil {
+		log.Warnf("replica %s ejected : %s", r.host, err)
+	} else if !wasHealthy && err == nil {
+		log.Infof("replica %s healthy", r.host)
+	}
+}
+
+func (p *replicaPool) statuses() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(p.replicas))
+	for _, r := range p.replicas {
+		r.mutex.RLock()
+		status := ReplicaStatus{Host: r.host, Healthy: r.healthy, Lag: r.lag, InUse: r.inUse()}
+		if r.err != nil {
+			status.Error = r.err.Error()
+		}
+		r.mutex.RUnlock()
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+// close stop the health check and close every replica connection
+func (p *replicaPool) close() error {
+	p.once.Do(func() {
+		close(p.stop)
+	})
+
+	var first error
+	for _, r := range p.replicas {
+		sqlDB, err := r.db.DB()
+		if err == nil {
+			err = sqlDB.Close()
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+func (r *replica) isHealthy() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	return r.healthy
+}
+
+func (r *replica) inUse() int {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return 0
+	}
+
+	return sqlDB.Stats().InUse
+}
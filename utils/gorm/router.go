@@ -0,0 +1,176 @@
+package gorm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// read after write mode of DB_READ_AFTER_WRITE_MODE
+const (
+	// ReadAfterWriteMaster read from the master during the window after a write
+	ReadAfterWriteMaster = "master"
+	// ReadAfterWriteWaitLSN read from the replica once it replayed the wal of the master, from the master
+	// when it is still behind after the max wait
+	ReadAfterWriteWaitLSN = "wait_lsn"
+)
+
+// lsnPollInterval interval of the replica replay check while waiting for its lsn
+const lsnPollInterval = 10 * time.Millisecond
+
+type sessionContextKey struct{}
+
+// session write of one request, shared by every query of the request through the context
+type session struct {
+	mutex   sync.Mutex
+	wroteAt time.Time
+}
+
+// NewSession context of a request or a message, a read after a write with the context see the write
+func NewSession(ctx context.Context) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, &session{})
+}
+
+// readRouter route the read of the replica to the master, or wait for the replica, after a write of the same
+// session or user, every other read go to the replica without delay
+type readRouter struct {
+	master  *gorm.DB
+	pool    *replicaPool
+	redis   redis.Iredis
+	window  time.Duration
+	mode    string
+	maxWait time.Duration
+}
+
+func newReadRouter(master *gorm.DB, pool *replicaPool, redis redis.Iredis, params DBParamSlaveConn) *readRouter {
+	router := &readRouter{
+		master:  master,
+		pool:    pool,
+		redis:   redis,
+		window:  time.Duration(params.ReadAfterWriteWindow) * time.Millisecond,
+		mode:    params.ReadAfterWriteMode,
+		maxWait: time.Duration(params.ReadAfterWriteMaxWait) * time.Millisecond,
+	}
+
+	// every write of the master, including the write of a transaction and Exec, mark the session
+	callback := master.Callback()
+	callback.Create().After("gorm:create").Register("read_after_write:create", router.markWrite)
+	callback.Update().After("gorm:update").Register("read_after_write:update", router.markWrite)
+	callback.Delete().After("gorm:delete").Register("read_after_write:delete", router.markWrite)
+	callback.Raw().After("gorm:raw").Register("read_after_write:raw", router.markWrite)
+
+	return router
+}
+
+// markWrite remember the write in the session of the context and in the marker of the user
+func (r *readRouter) markWrite(db *gorm.DB) {
+	if db.Error != nil || r.window <= 0 {
+		return
+	}
+
+	ctx := db.Statement.Context
+	if ctx == nil {
+		return
+	}
+
+	if s, ok := ctx.Value(sessionContextKey{}).(*session); ok {
+		s.mutex.Lock()
+		s.wroteAt = time.Now()
+		s.mutex.Unlock()
+	}
+
+	if userId, ok := ctx.Value(helper.UserIDContextKey).(string); ok && userId != "" && r.redis != nil {
+		err := r.redis.Set(ctx, userMarkerKey(userId), time.Now().UnixMilli(), r.window)
+		if err != nil {
+			log.Warnf("failed to mark write of user %s : %s", userId, err)
+		}
+	}
+}
+
+// route database of the read, the master when no replica is healthy
+func (r *readRouter) route(ctx context.Context) *gorm.DB {
+	replica := r.pool.pick()
+	if replica == nil {
+		return r.master
+	}
+
+	if !r.wrote(ctx) {
+		return replica
+	}
+
+	if r.mode == ReadAfterWriteWaitLSN && r.caughtUp(ctx, replica) {
+		return replica
+	}
+
+	return r.master
+}
+
+// wrote the session or the user wrote within the window
+func (r *readRouter) wrote(ctx context.Context) bool {
+	if r.window <= 0 {
+		return false
+	}
+
+	if s, ok := ctx.Value(sessionContextKey{}).(*session); ok {
+		s.mutex.Lock()
+		wroteAt := s.wroteAt
+		s.mutex.Unlock()
+
+		if !wroteAt.IsZero() && time.Since(wroteAt) < r.window {
+			return true
+		}
+	}
+
+	if userId, ok := ctx.Value(helper.UserIDContextKey).(string); ok && userId != "" && r.redis != nil {
+		var wroteAt int64
+		err := r.redis.Get(ctx, userMarkerKey(userId), &wroteAt)
+		if err == nil && wroteAt > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// caughtUp wait until the replica replayed the current wal of the master, at most the max wait
+func (r *readRouter) caughtUp(ctx context.Context, replica *gorm.DB) bool {
+	var lsn string
+
+	err := r.master.WithContext(ctx).Raw("SELECT pg_current_wal_lsn()::text").Scan(&lsn).Error
+	if err != nil {
+		return false
+	}
+
+	deadline := time.Now().Add(r.maxWait)
+	for {
+		var replayed bool
+
+		err := replica.WithContext(ctx).Raw("SELECT COALESCE(pg_last_wal_replay_lsn() >= ?::pg_lsn, true)", lsn).Scan(&replayed).Error
+		if err != nil {
+			return false
+		}
+		if replayed {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(lsnPollInterval):
+		}
+	}
+}
+
+func userMarkerKey(userId string) string {
+	return "read_after_write:user:" + userId
+}
@@ -0,0 +1,247 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultStopTimeout used when the hook and the param have no stop timeout
+const defaultStopTimeout = 10 * time.Second
+
+// Hook component of the process. Start must not block, a long running component run in its own goroutine
+// until Stop. Stop get a context with the deadline of the component, Timeout zero use the default of the manager
+type Hook struct {
+	Name    string
+	Start   func(ctx context.Context) error
+	Stop    func(ctx context.Context) error
+	Timeout time.Duration
+}
+
+type Param struct {
+	// StopTimeout default deadline of a hook
+	StopTimeout time.Duration
+}
+
+// Manager start the hook in the order they are appended and stop them in the reverse order, the process is ready
+// between the last start and the first stop
+type Manager struct {
+	stopTimeout time.Duration
+	mutex       sync.Mutex
+	hooks       []Hook
+	// started number of hook started, only those are stopped
+	started int
+	ready   int32
+}
+
+func NewManager(param Param) *Manager {
+	stopTimeout := param.StopTimeout
+	if stopTimeout <= 0 {
+		stopTimeout = defaultStopTimeout
+	}
+
+	return &Manager{
+		stopTimeout: stopTimeout,
+	}
+}
+
+// Append add the hook, a hook appended after Start is not started
+func (m *Manager) Append(hooks ...Hook) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.hooks = append(m.hooks, hooks...)
+}
+
+// Ready the process is started and not stopping, used by the readiness probe
+func (m *Manager) Ready() bool {
+	return atomic.LoadInt32(&m.ready) == 1
+}
+
+// Start start every hook in order and flip the process ready, when a hook fail the started hook are stopped
+func (m *Manager) Start(ctx context.Context) error {
+	m.mutex.Lock()
+	hooks := m.hooks
+	m.mutex.Unlock()
+
+	for i, hook := range hooks {
+		if hook.Start != nil {
+			err := hook.Start(ctx)
+			if err != nil {
+				log.Errorf("failed to start %s : %s", hook.Name, err)
+				m.stop(ctx, hooks[:i])
+				return fmt.Errorf("start %s: %w", hook.Name, err)
+			}
+		}
+
+		log.Infof("%s started", hook.Name)
+	}
+
+	m.mutex.Lock()
+	m.started = len(hooks)
+	m.mutex.Unlock()
+
+	atomic.StoreInt32(&m.ready, 1)
+
+	return nil
+}
+
+// Stop flip the process not ready and stop the started hook in reverse order.
+// Every hook is stopped even when a previous one failed, the first error is returned
+func (m *Manager) Stop(ctx context.Context) error {
+	atomic.StoreInt32(&m.ready, 0)
+
+	m.mutex.Lock()
+	hooks := m.hooks[:m.started]
+	m.started = 0
+	m.mutex.Unlock()
+
+	return m.stop(ctx, hooks)
+}
+
+// Run start every hook and stop them on SIGINT, SIGTERM or when the context is done
+func (m *Manager) Run(ctx context.Context) error {
+	err := m.Start(ctx)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case sig := <-signals:
+		log.Infof("got signal %s, stopping", sig)
+	case <-ctx.Done():
+		log.Info("context done, stopping")
+	}
+
+	// the stop must not be cut short by the context that triggered it
+	return m.Stop(context.Background())
+}
+
+// Background hook of a component that run until its context is cancelled, Stop cancel the context and wait
+// for run to return
+func Background(name string, timeout time.Duration, run func(ctx context.Context)) Hook {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+
+	return Hook{
+		Name:    name,
+		Timeout: timeout,
+		Start: func(ctx context.Context) error {
+			ctx, cancel = context.WithCancel(ctx)
+
+			go func() {
+				defer close(done)
+				run(ctx)
+			}()
+
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// Drain hook that wait for the delay on stop, appended after the http server it keep the server running
+// while the load balancer notice the process is not ready anymore
+func Drain(delay time.Duration) Hook {
+	return Hook{
+		Name:    "readiness drain",
+		Timeout: delay + time.Second,
+		Stop: func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+				return nil
+			}
+		},
+	}
+}
+
+// WithoutCancel keep the value of the context without its cancellation, so the work in process of a stopped
+// component, e.g. a consumed message, is finished instead of cut short
+func WithoutCancel(ctx context.Context) context.Context {
+	return withoutCancel{ctx}
+}
+
+type withoutCancel struct {
+	context.Context
+}
+
+func (withoutCancel) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (withoutCancel) Done() <-chan struct{} {
+	return nil
+}
+
+func (withoutCancel) Err() error {
+	return nil
+}
+
+func (m *Manager) stop(ctx context.Context, hooks []Hook) error {
+	var first error
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.Stop == nil {
+			continue
+		}
+
+		timeout := hook.Timeout
+		if timeout <= 0 {
+			timeout = m.stopTimeout
+		}
+
+		err := stopHook(ctx, hook, timeout)
+		if err != nil {
+			log.Errorf("failed to stop %s : %s", hook.Name, err)
+			if first == nil {
+				first = fmt.Errorf("stop %s: %w", hook.Name, err)
+			}
+			continue
+		}
+
+		log.Infof("%s stopped", hook.Name)
+	}
+
+	return first
+}
+
+// stopHook run the stop of the hook until its deadline, a stop still running at the deadline is abandoned
+func stopHook(ctx context.Context, hook Hook, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("deadline of %s exceeded: %w", timeout, ctx.Err())
+	}
+}
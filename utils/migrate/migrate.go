@@ -0,0 +1,342 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockKey postgres advisory lock held while migrating, a second runner wait until the first is done
+const lockKey int64 = 4815162342
+
+const createTable = `CREATE TABLE IF NOT EXISTS public.schema_migrations (
+	"version" int8 NOT NULL,
+	"name" varchar(100) NOT NULL,
+	checksum varchar(64) NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now(),
+	CONSTRAINT schema_migrations_pkey PRIMARY KEY ("version")
+)`
+
+// ErrChecksumMismatch an applied migration was edited, add a new migration instead of changing an applied one
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// Migration pair of <version>_<name>.up.sql and <version>_<name>.down.sql, the checksum is the one of the up file
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status of a migration, a migration applied by a newer release has no file and Unknown is true
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool
+	Unknown   bool
+}
+
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator migrator of the migration file in the root of fsys
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Load read the migration file sorted by version, every version must have an up file
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := base[strings.LastIndex(base, ".")+1:]
+		base = strings.TrimSuffix(base, "."+direction)
+
+		part := strings.SplitN(base, "_", 2)
+		version, err := strconv.ParseInt(part[0], 10, 64)
+		if err != nil || len(part) != 2 || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s, want <version>_<name>.<up|down>.sql", entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: part[1]}
+			byVersion[version] = migration
+		}
+		if migration.Name != part[1] {
+			return nil, fmt.Errorf("migration %d has two name %s and %s", version, migration.Name, part[1])
+		}
+
+		if direction == "up" {
+			sum := sha256.Sum256(content)
+			migration.Up = string(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up apply the pending migration in version order, steps zero apply every pending migration.
+// Every migration run in its own transaction with its schema_migrations row
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn, history map[int64]applied) error {
+		err := m.verify(history)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := history[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && len(done) == steps {
+				break
+			}
+
+			err := run(ctx, conn, migration.Up,
+				`INSERT INTO public.schema_migrations ("version", "name", checksum, applied_at) VALUES ($1, $2, $3, now())`,
+				migration.Version, migration.Name, migration.Checksum)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down revert the last applied migration, steps zero revert one migration
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+
+	var done []Migration
+
+	err := m.locked(ctx, func(conn *sql.Conn, history map[int64]applied) error {
+		err := m.verify(history)
+		if err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(history))
+		for version := range history {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool {
+			return versions[i] > versions[j]
+		})
+
+		for _, version := range versions {
+			if len(done) == steps {
+				break
+			}
+
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("migration %d_%s is applied but has no file", version, history[version].name)
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			err := run(ctx, conn, migration.Down, `DELETE FROM public.schema_migrations WHERE "version" = $1`, migration.Version)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+			}
+
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Status every migration file and every applied migration sorted by version
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+
+	err := m.locked(ctx, func(conn *sql.Conn, history map[int64]applied) error {
+		for _, migration := range m.migrations {
+			status := Status{Version: migration.Version, Name: migration.Name}
+			if row, ok := history[migration.Version]; ok {
+				status.Applied = true
+				status.AppliedAt = row.appliedAt
+				status.Modified = row.checksum != migration.Checksum
+			}
+			statuses = append(statuses, status)
+		}
+
+		for version, row := range history {
+			if _, ok := m.find(version); !ok {
+				statuses = append(statuses, Status{Version: version, Name: row.name, Applied: true, AppliedAt: row.appliedAt, Unknown: true})
+			}
+		}
+
+		return nil
+	})
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, err
+}
+
+// locked run fn on one connection holding the advisory lock, with the schema_migrations table created
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, history map[int64]applied) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey)
+	if err != nil {
+		return fmt.Errorf("lock schema_migrations: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	_, err = conn.ExecContext(ctx, createTable)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	history, err := readHistory(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, history)
+}
+
+// verify every applied migration still has the checksum it was applied with
+func (m *Migrator) verify(history map[int64]applied) error {
+	for _, migration := range m.migrations {
+		row, ok := history[migration.Version]
+		if ok && row.checksum != migration.Checksum {
+			return fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, ErrChecksumMismatch)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+
+	return Migration{}, false
+}
+
+func readHistory(ctx context.Context, conn *sql.Conn) (map[int64]applied, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT "version", "name", checksum, applied_at FROM public.schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := map[int64]applied{}
+	for rows.Next() {
+		var (
+			version int64
+			row     applied
+		)
+
+		err := rows.Scan(&version, &row.name, &row.checksum, &row.appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		history[version] = row
+	}
+
+	return history, rows.Err()
+}
+
+// run the script and record it in one transaction, a failed script leave no trace
+func run(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,128 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/config"
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+)
+
+// charge and refund status, provider specific status must be mapped to one of these
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+var (
+	ErrProviderNotFound = errors.New("payment provider not found")
+	// ErrChargeNotFound provider has no charge with the reference
+	ErrChargeNotFound = errors.New("charge not found")
+)
+
+type ChargeRequest struct {
+	// IdempotencyKey unique per attempt, provider must return the same charge when it is sent twice
+	IdempotencyKey string
+	OrderId        string
+	PaymentMethod  string
+	Amount         int64
+}
+
+type ChargeResult struct {
+	Reference string
+	Status    string
+	// Amount charged by the provider, zero when the provider does not report it
+	Amount        int64
+	FailureReason string
+	PaidAt        *time.Time
+}
+
+type RefundRequest struct {
+	IdempotencyKey string
+	Reference      string
+	Amount         int64
+	Reason         string
+}
+
+type RefundResult struct {
+	Reference     string
+	Status        string
+	FailureReason string
+}
+
+// PaymentProvider gateway used to collect the payment of an order
+type PaymentProvider interface {
+	Name() string
+	CreateCharge(ctx context.Context, request ChargeRequest) (ChargeResult, error)
+	QueryStatus(ctx context.Context, reference string) (ChargeResult, error)
+	// Refund sent again with the same idempotency key return the current result of the refund
+	Refund(ctx context.Context, request RefundRequest) (RefundResult, error)
+}
+
+type IRegistry interface {
+	Get(paymentMethod string) (PaymentProvider, error)
+	Provider(name string) (PaymentProvider, error)
+}
+
+// Registry select the provider of an order from its payment method
+type Registry struct {
+	providers       map[string]PaymentProvider
+	methods         map[string]string
+	defaultProvider string
+}
+
+// NewRegistry build the registry from config, PAYMENT_METHOD_PROVIDER map payment method to provider
+// with format "method:provider,method:provider", other method use PAYMENT_DEFAULT_PROVIDER
+func NewRegistry(redis redis.Iredis) IRegistry {
+	cfg := config.Get().Payment
+
+	r := &Registry{
+		providers:       map[string]PaymentProvider{},
+		methods:         ParseMethodProvider(cfg.MethodProvider),
+		defaultProvider: cfg.DefaultProvider,
+	}
+
+	r.Register(NewSimulator(cfg.Simulator.Scenario, time.Duration(cfg.Simulator.Delay)*time.Second, redis))
+
+	return r
+}
+
+func (r *Registry) Register(provider PaymentProvider) {
+	r.providers[provider.Name()] = provider
+}
+
+func (r *Registry) Get(paymentMethod string) (PaymentProvider, error) {
+	name, ok := r.methods[strings.ToLower(paymentMethod)]
+	if !ok {
+		name = r.defaultProvider
+	}
+
+	return r.Provider(name)
+}
+
+func (r *Registry) Provider(name string) (PaymentProvider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+
+	return provider, nil
+}
+
+func ParseMethodProvider(value string) map[string]string {
+	methods := map[string]string{}
+
+	for _, pair := range strings.Split(value, ",") {
+		method, provider, ok := strings.Cut(pair, ":")
+		if !ok {
+			continue
+		}
+
+		methods[strings.ToLower(strings.TrimSpace(method))] = strings.TrimSpace(provider)
+	}
+
+	return methods
+}
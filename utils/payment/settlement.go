@@ -0,0 +1,89 @@
+package payment
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSettlement = errors.New("invalid settlement report")
+
+// settlementColumn column of the settlement report, settled_at is optional
+var settlementColumn = []string{"reference", "order_id", "amount", "status"}
+
+// Settlement one charge of the provider settlement report
+type Settlement struct {
+	Reference string
+	OrderId   string
+	Amount    int64
+	Status    string
+	SettledAt *time.Time
+}
+
+// ParseSettlement read settlement report csv, the first line is the header and column can be in any order.
+// Provider status is mapped to our charge status, settled_at use RFC3339 format
+func ParseSettlement(r io.Reader) ([]Settlement, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSettlement, err)
+	}
+
+	index := map[string]int{}
+	for i, column := range header {
+		index[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	for _, column := range settlementColumn {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("%w: missing column %s", ErrInvalidSettlement, column)
+		}
+	}
+
+	settlement := []Settlement{}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSettlement, err)
+		}
+
+		amount, err := strconv.ParseInt(record[index["amount"]], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d invalid amount %s", ErrInvalidSettlement, line, record[index["amount"]])
+		}
+
+		status, ok := MapStatus(record[index["status"]])
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d unknown status %s", ErrInvalidSettlement, line, record[index["status"]])
+		}
+
+		row := Settlement{
+			Reference: record[index["reference"]],
+			OrderId:   record[index["order_id"]],
+			Amount:    amount,
+			Status:    status,
+		}
+
+		if i, ok := index["settled_at"]; ok && record[i] != "" {
+			settledAt, err := time.Parse(time.RFC3339, record[i])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d invalid settled_at %s", ErrInvalidSettlement, line, record[i])
+			}
+			row.SettledAt = &settledAt
+		}
+
+		settlement = append(settlement, row)
+	}
+
+	return settlement, nil
+}
@@ -0,0 +1,78 @@
+package payment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidTimestamp = errors.New("webhook timestamp outside replay window")
+)
+
+// Sign compute hex HMAC-SHA256 of "<timestamp>.<body>"
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature check the signature of a webhook and reject timestamp (unix second) older or newer than tolerance
+func VerifySignature(secret string, timestamp string, body []byte, signature string, tolerance time.Duration, now time.Time) error {
+	// webhook is disabled until a secret is configured
+	if secret == "" {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+
+	diff := now.Sub(time.Unix(unix, 0))
+	if diff < 0 {
+		diff = -diff
+	}
+
+	if diff > tolerance {
+		return ErrInvalidTimestamp
+	}
+
+	expected := Sign(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(signature))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// webhookStatus provider status of a charge mapped to our charge status
+var webhookStatus = map[string]string{
+	"pending":    StatusPending,
+	"authorize":  StatusPending,
+	"success":    StatusSuccess,
+	"succeeded":  StatusSuccess,
+	"paid":       StatusSuccess,
+	"settlement": StatusSuccess,
+	"capture":    StatusSuccess,
+	"failed":     StatusFailed,
+	"failure":    StatusFailed,
+	"deny":       StatusFailed,
+	"cancel":     StatusFailed,
+	"expire":     StatusFailed,
+	"expired":    StatusFailed,
+}
+
+// MapStatus map the status sent by provider, unknown status return false
+func MapStatus(status string) (string, bool) {
+	mapped, ok := webhookStatus[strings.ToLower(status)]
+	return mapped, ok
+}
@@ -0,0 +1,212 @@
+package payment
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/utils/redis"
+
+	"github.com/bwmarrin/snowflake"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+const SimulatorName = "simulator"
+
+// simulator scenario
+const (
+	// ScenarioSuccess charge is paid immediately
+	ScenarioSuccess = "success"
+	// ScenarioFailure charge is declined immediately
+	ScenarioFailure = "failure"
+	// ScenarioDelay charge stay pending until the delay has passed, then it is paid
+	ScenarioDelay = "delay"
+)
+
+// simulatorTTL time a simulated charge is kept
+const simulatorTTL = 30 * 24 * time.Hour
+
+// simulator key in redis, the charge is shared by every process (server, consumer, reconcile) and survive a restart
+const (
+	simulatorChargeKey            = "payment_simulator:charge:%s"
+	simulatorChargeIdempotencyKey = "payment_simulator:charge_key:%s"
+	simulatorRefundIdempotencyKey = "payment_simulator:refund_key:%s"
+)
+
+type simulatedCharge struct {
+	Result    ChargeResult `json:"result"`
+	CreatedAt time.Time    `json:"created_at"`
+	Amount    int64        `json:"amount"`
+	Refunded  int64        `json:"refunded"`
+}
+
+// Simulator local provider for development and tests, no money is moved
+type Simulator struct {
+	Scenario string
+	Delay    time.Duration
+
+	mu    sync.Mutex
+	node  *snowflake.Node
+	redis redis.Iredis
+}
+
+func NewSimulator(scenario string, delay time.Duration, redis redis.Iredis) *Simulator {
+	node, _ := snowflake.NewNode(0)
+
+	switch scenario {
+	case ScenarioSuccess, ScenarioFailure, ScenarioDelay:
+	default:
+		scenario = ScenarioSuccess
+	}
+
+	return &Simulator{
+		Scenario: scenario,
+		Delay:    delay,
+		node:     node,
+		redis:    redis,
+	}
+}
+
+func (s *Simulator) Name() string {
+	return SimulatorName
+}
+
+func (s *Simulator) CreateCharge(ctx context.Context, request ChargeRequest) (ChargeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	charge := simulatedCharge{
+		Result: ChargeResult{
+			Reference: "SIM-" + s.node.Generate().String(),
+			Status:    StatusPending,
+			Amount:    request.Amount,
+		},
+		CreatedAt: now,
+		Amount:    request.Amount,
+	}
+
+	// the same idempotency key return the charge created first
+	isNew, err := s.redis.SetNX(ctx, fmt.Sprintf(simulatorChargeIdempotencyKey, request.IdempotencyKey), charge.Result.Reference, simulatorTTL)
+	if err != nil {
+		return ChargeResult{}, err
+	}
+	if !isNew {
+		var reference string
+		err = s.redis.Get(ctx, fmt.Sprintf(simulatorChargeIdempotencyKey, request.IdempotencyKey), &reference)
+		if err != nil {
+			return ChargeResult{}, err
+		}
+		return s.status(ctx, reference)
+	}
+
+	switch s.Scenario {
+	case ScenarioSuccess:
+		charge.Result.Status = StatusSuccess
+		charge.Result.PaidAt = &now
+	case ScenarioFailure:
+		charge.Result.Status = StatusFailed
+		charge.Result.FailureReason = "declined by simulator"
+	}
+
+	err = s.save(ctx, charge)
+	if err != nil {
+		return ChargeResult{}, err
+	}
+
+	return charge.Result, nil
+}
+
+func (s *Simulator) QueryStatus(ctx context.Context, reference string) (ChargeResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.status(ctx, reference)
+}
+
+func (s *Simulator) Refund(ctx context.Context, request RefundRequest) (RefundResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := RefundResult{
+		Reference: "SIMR-" + s.node.Generate().String(),
+	}
+
+	if request.IdempotencyKey != "" {
+		var previous RefundResult
+		err := s.redis.Get(ctx, fmt.Sprintf(simulatorRefundIdempotencyKey, request.IdempotencyKey), &previous)
+		if err == nil {
+			return previous, nil
+		}
+		if err != goredis.Nil {
+			return RefundResult{}, err
+		}
+	}
+
+	charge, err := s.load(ctx, request.Reference)
+	if err != nil {
+		return RefundResult{}, err
+	}
+
+	switch {
+	case charge.Result.Status != StatusSuccess:
+		result.Status = StatusFailed
+		result.FailureReason = "charge is not paid"
+	case charge.Refunded+request.Amount > charge.Amount:
+		result.Status = StatusFailed
+		result.FailureReason = "refund amount exceed paid amount"
+	default:
+		charge.Refunded += request.Amount
+		result.Status = StatusSuccess
+
+		err = s.save(ctx, charge)
+		if err != nil {
+			return RefundResult{}, err
+		}
+	}
+
+	if request.IdempotencyKey != "" {
+		err = s.redis.Set(ctx, fmt.Sprintf(simulatorRefundIdempotencyKey, request.IdempotencyKey), result, simulatorTTL)
+		if err != nil {
+			return RefundResult{}, err
+		}
+	}
+
+	return result, nil
+}
+
+func (s *Simulator) status(ctx context.Context, reference string) (ChargeResult, error) {
+	charge, err := s.load(ctx, reference)
+	if err != nil {
+		return ChargeResult{}, err
+	}
+
+	if charge.Result.Status == StatusPending && time.Since(charge.CreatedAt) >= s.Delay {
+		paidAt := charge.CreatedAt.Add(s.Delay)
+		charge.Result.Status = StatusSuccess
+		charge.Result.PaidAt = &paidAt
+
+		err = s.save(ctx, charge)
+		if err != nil {
+			return ChargeResult{}, err
+		}
+	}
+
+	return charge.Result, nil
+}
+
+func (s *Simulator) load(ctx context.Context, reference string) (simulatedCharge, error) {
+	var charge simulatedCharge
+
+	err := s.redis.Get(ctx, fmt.Sprintf(simulatorChargeKey, reference), &charge)
+	if err == goredis.Nil {
+		return charge, ErrChargeNotFound
+	}
+
+	return charge, err
+}
+
+func (s *Simulator) save(ctx context.Context, charge simulatedCharge) error {
+	return s.redis.Set(ctx, fmt.Sprintf(simulatorChargeKey, charge.Result.Reference), charge, simulatorTTL)
+}
@@ -0,0 +1,169 @@
+package rabbitmq
+
+import (
+	"context"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/gorm"
+
+	"github.com/gin-gonic/gin/binding"
+	amqp "github.com/rabbitmq/amqp091-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// ConsumerOptions zero value use the connection default, prefetch default to the concurrency.
+// RateLimit is the maximum message per second handled by every worker of the consumer together, zero is unlimited
+type ConsumerOptions struct {
+	Concurrency int
+	Prefetch    int
+	RateLimit   float64
+	Retry       RetryPolicy
+}
+
+// Consumer queue consumed by a worker, the delivery is acknowledged when the worker succeed and retried otherwise
+type Consumer struct {
+	Queue   string
+	Options ConsumerOptions
+	Worker  func(ctx context.Context, d amqp.Delivery) error
+}
+
+// Handle register the handler of the queue with the payload type T. Only the schema version of T is accepted,
+// see HandleVersions
+func Handle[T any](queue string, handler func(ctx context.Context, payload T) error, options ConsumerOptions) Consumer {
+	var payload T
+
+	return HandleVersions(queue, handler, Decoders[T]{SchemaVersion(payload): DecodeJSON[T]}, options)
+}
+
+// HandleVersions register the handler of the queue with a decoder for every accepted schema version. The body is
+// decoded by the decoder of the envelope version into T and validated with the binding tag before the handler is
+// called, with the message id as request id and the envelope in the context. A payload of an unknown version,
+// that can not be decoded or is not valid goes to the dead letter queue without retry
+func HandleVersions[T any](queue string, handler func(ctx context.Context, payload T) error, decoders Decoders[T], options ConsumerOptions) Consumer {
+	return Consumer{
+		Queue:   queue,
+		Options: options,
+		Worker: func(ctx context.Context, d amqp.Delivery) error {
+			envelope := EnvelopeOf(d)
+
+			ctx = helper.SetRequestIDToContext(ctx, d.MessageId)
+			ctx = WithEnvelope(ctx, envelope)
+
+			payload, err := decodePayload(envelope, d.Body, decoders)
+			if err != nil {
+				return err
+			}
+
+			err = binding.Validator.ValidateStruct(payload)
+			if err != nil {
+				return Permanent(err)
+			}
+
+			return handler(ctx, payload)
+		},
+	}
+}
+
+// concurrency number of worker of the consumer
+func (o ConsumerOptions) concurrency(fallback int) int {
+	if o.Concurrency > 0 {
+		return o.Concurrency
+	}
+	if fallback > 0 {
+		return fallback
+	}
+
+	return 1
+}
+
+// prefetch number of unacknowledged delivery of the consumer channel
+func (o ConsumerOptions) prefetch(concurrency int) int {
+	if o.Prefetch > 0 {
+		return o.Prefetch
+	}
+
+	return concurrency
+}
+
+// limiter release one message every interval of the rate limit, a nil limiter does not wait
+func (o ConsumerOptions) limiter() *time.Ticker {
+	if o.RateLimit <= 0 {
+		return nil
+	}
+
+	return time.NewTicker(time.Duration(float64(time.Second) / o.RateLimit))
+}
+
+// handleMessage run the worker and acknowledge the delivery, a failed delivery is retried. Every delivery has its
+// own database session, a read of the worker after its write see the write
+func handleMessage(ctx context.Context, mq IRabbitMQ, consumer Consumer, message amqp.Delivery) {
+	ctx = gorm.NewSession(ctx)
+
+	envelope := EnvelopeOf(message)
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("consumer %s consume message id %s %s v%d correlation id %s with body : %v", message.ConsumerTag, message.MessageId, envelope.EventType, envelope.SchemaVersion, envelope.CorrelationId, string(message.Body))
+
+	err := consumer.Worker(ctx, message)
+	if err != nil {
+		retryMessage(ctx, mq, consumer.Queue, message, err, consumer.Options.Retry)
+		return
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("consumer %s ack message id %s with body : %v", message.ConsumerTag, message.MessageId, string(message.Body))
+	message.Ack(false)
+}
+
+// retryMessage republish the failed delivery to the ttl retry queue with the attempt count, after the last attempt
+// it is parked in the dead letter queue with the failure reason. The delivery is only acknowledged once the
+// message has been republished, otherwise it is requeued. A requeue error is retried without counting the attempt
+func retryMessage(ctx context.Context, mq IRabbitMQ, queue string, d amqp.Delivery, failure error, retry RetryPolicy) {
+	attempt := Attempt(d)
+	if !IsRequeue(failure) {
+		attempt++
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderAttempt] = int32(attempt)
+
+	message := amqp.Publishing{
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+		ContentType:  d.ContentType,
+		Body:         d.Body,
+		MessageId:    d.MessageId,
+		Timestamp:    d.Timestamp,
+		Type:         d.Type,
+	}
+
+	var err error
+
+	if retry.ShouldRetry(attempt, failure) {
+		delay := retry.Delay(attempt)
+		if IsRequeue(failure) {
+			log.WithField(helper.GetRequestIDContext(ctx)).Infof("consumer %s requeue message id %s in %s : %s", d.ConsumerTag, d.MessageId, delay, failure)
+		} else {
+			log.WithField(helper.GetRequestIDContext(ctx)).Warnf("consumer %s attempt %d of message id %s failed, retry in %s : %s", d.ConsumerTag, attempt, d.MessageId, delay, failure)
+		}
+
+		err = mq.PublishMessageToDeathLetter(ctx, queue, message, int(delay.Milliseconds()))
+	} else {
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("consumer %s attempt %d of message id %s failed, moved to %s : %s", d.ConsumerTag, attempt, d.MessageId, DeadLetterQueue(queue), failure)
+
+		headers[HeaderFailureReason] = failure.Error()
+		headers[HeaderOriginalQueue] = queue
+		headers[HeaderFailedAt] = time.Now().UTC().Format(time.RFC3339)
+
+		err = mq.PublishMessageToDLQ(ctx, queue, message)
+	}
+
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Infof("consumer %s nack message id %s with body : %v", d.ConsumerTag, d.MessageId, string(d.Body))
+		d.Nack(false, true)
+		return
+	}
+
+	d.Ack(false)
+}
@@ -0,0 +1,224 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeadLetterMessage message parked in the dead letter queue of a queue
+type DeadLetterMessage struct {
+	MessageId     string                 `json:"message_id"`
+	OriginalQueue string                 `json:"original_queue"`
+	Attempt       int                    `json:"attempt"`
+	FailureReason string                 `json:"failure_reason"`
+	FailedAt      string                 `json:"failed_at"`
+	Headers       map[string]interface{} `json:"headers"`
+	Body          string                 `json:"body"`
+}
+
+// ListDeadLetter peek up to limit message of the dead letter queue, the message stay in the queue
+func (mq *RabbitMQ) ListDeadLetter(ctx context.Context, queue string, limit int) ([]DeadLetterMessage, error) {
+	message := []DeadLetterMessage{}
+
+	err := mq.walkDeadLetter(ctx, queue, limit, func(ch *confirmChannel, d amqp.Delivery) (bool, error) {
+		message = append(message, newDeadLetterMessage(queue, d))
+		return false, nil
+	})
+
+	return message, err
+}
+
+// ReplayDeadLetter publish the message back to its original queue with a fresh attempt count,
+// without message id every message is replayed. Return the number of replayed message
+func (mq *RabbitMQ) ReplayDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error) {
+	selected := selectMessage(messageIds)
+	replayed := 0
+
+	err := mq.walkDeadLetter(ctx, queue, 0, func(ch *confirmChannel, d amqp.Delivery) (bool, error) {
+		if !selected(d.MessageId) {
+			return false, nil
+		}
+
+		original, message := replayMessage(queue, d)
+
+		// the dead letter message is only acknowledged once the broker confirm the replay
+		err := ch.publish(ctx, "", original, message, mq.confirmTimeout)
+		if err != nil {
+			return false, err
+		}
+
+		replayed++
+		return true, nil
+	})
+
+	return replayed, err
+}
+
+// PurgeDeadLetter delete the message from the dead letter queue, without message id the queue is emptied.
+// Return the number of deleted message
+func (mq *RabbitMQ) PurgeDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error) {
+	if len(messageIds) == 0 {
+		ch, err := mq.adminChannel(ctx)
+		if err != nil {
+			return 0, err
+		}
+		defer ch.Close()
+
+		purged, err := ch.QueuePurge(DeadLetterQueue(queue), false)
+		if isNotFound(err) {
+			return 0, nil
+		}
+
+		return purged, err
+	}
+
+	selected := selectMessage(messageIds)
+	purged := 0
+
+	err := mq.walkDeadLetter(ctx, queue, 0, func(ch *confirmChannel, d amqp.Delivery) (bool, error) {
+		if !selected(d.MessageId) {
+			return false, nil
+		}
+
+		purged++
+		return true, nil
+	})
+
+	return purged, err
+}
+
+// adminChannel separate channel for dead letter tooling, closing it requeue every message that is not acknowledged
+func (mq *RabbitMQ) adminChannel(ctx context.Context) (*confirmChannel, error) {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+
+	err := mq.ensureConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newConfirmChannel(mq.connection)
+}
+
+// walkDeadLetter get every message currently in the dead letter queue once, message handled by fn is
+// acknowledged and the others are requeued when the channel is closed
+func (mq *RabbitMQ) walkDeadLetter(ctx context.Context, queue string, limit int, fn func(ch *confirmChannel, d amqp.Delivery) (bool, error)) error {
+	ch, err := mq.adminChannel(ctx)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	q, err := ch.QueueDeclarePassive(DeadLetterQueue(queue), true, false, false, false, nil)
+	if err != nil {
+		// nothing has been dead lettered yet
+		if isNotFound(err) {
+			return nil
+		}
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while declare queue : %s", err.Error())
+		return err
+	}
+
+	count := q.Messages
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	for i := 0; i < count; i++ {
+		d, ok, err := ch.Get(q.Name, false)
+		if err != nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while get message : %s", err.Error())
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		handled, err := fn(ch, d)
+		if err != nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while handle message id %s : %s", d.MessageId, err.Error())
+			return err
+		}
+
+		if handled {
+			err = d.Ack(false)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// replayMessage original queue of the dead letter message and the message to publish there,
+// the retry header is removed so the message get every attempt again
+func replayMessage(queue string, d amqp.Delivery) (string, amqp.Publishing) {
+	original := queue
+	if v, ok := d.Headers[HeaderOriginalQueue].(string); ok && v != "" {
+		original = v
+	}
+
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		switch k {
+		case HeaderAttempt, HeaderFailureReason, HeaderOriginalQueue, HeaderFailedAt, "x-death":
+		default:
+			headers[k] = v
+		}
+	}
+
+	return original, amqp.Publishing{
+		Headers:      headers,
+		DeliveryMode: amqp.Persistent,
+		ContentType:  d.ContentType,
+		Body:         d.Body,
+		MessageId:    d.MessageId,
+		Timestamp:    d.Timestamp,
+		Type:         d.Type,
+	}
+}
+
+func newDeadLetterMessage(queue string, d amqp.Delivery) DeadLetterMessage {
+	message := DeadLetterMessage{
+		MessageId:     d.MessageId,
+		OriginalQueue: queue,
+		Attempt:       Attempt(d),
+		Headers:       d.Headers,
+		Body:          string(d.Body),
+	}
+
+	if v, ok := d.Headers[HeaderOriginalQueue].(string); ok && v != "" {
+		message.OriginalQueue = v
+	}
+	if v, ok := d.Headers[HeaderFailureReason].(string); ok {
+		message.FailureReason = v
+	}
+	if v, ok := d.Headers[HeaderFailedAt].(string); ok {
+		message.FailedAt = v
+	}
+
+	return message
+}
+
+// selectMessage match every message when no message id is given
+func selectMessage(messageIds []string) func(messageId string) bool {
+	ids := make(map[string]bool, len(messageIds))
+	for _, id := range messageIds {
+		ids[id] = true
+	}
+
+	return func(messageId string) bool {
+		return len(ids) == 0 || ids[messageId]
+	}
+}
+
+func isNotFound(err error) bool {
+	var amqpErr *amqp.Error
+	return errors.As(err, &amqpErr) && amqpErr.Code == amqp.NotFound
+}
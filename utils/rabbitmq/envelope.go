@@ -0,0 +1,289 @@
+package rabbitmq
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// envelope header of every published message, traceparent and tracestate follow the W3C trace context
+const (
+	HeaderEventType     = "x-event-type"
+	HeaderSchemaVersion = "x-schema-version"
+	HeaderProducedAt    = "x-produced-at"
+	HeaderProducer      = "x-producer"
+	HeaderCorrelationId = "x-correlation-id"
+	HeaderCausationId   = "x-causation-id"
+	HeaderTraceParent   = "traceparent"
+	HeaderTraceState    = "tracestate"
+)
+
+// defaultSchemaVersion version of a payload without SchemaVersion and of a message published before the envelope
+const defaultSchemaVersion = 1
+
+// ErrUnknownSchemaVersion the consumer has no decoder for the schema version of the message
+var ErrUnknownSchemaVersion = errors.New("unknown schema version")
+
+// Envelope metadata of a message carried in the header, the body is the payload only.
+// CorrelationId is shared by every message caused by the same request, CausationId is the message id
+// of the message being consumed when this one is published
+type Envelope struct {
+	EventType     string    `json:"event_type"`
+	SchemaVersion int       `json:"schema_version"`
+	ProducedAt    time.Time `json:"produced_at"`
+	Producer      string    `json:"producer"`
+	CorrelationId string    `json:"correlation_id"`
+	CausationId   string    `json:"causation_id,omitempty"`
+	TraceParent   string    `json:"traceparent"`
+	TraceState    string    `json:"tracestate,omitempty"`
+	// MessageId of the consumed message, it is the message property and not a header
+	MessageId string `json:"-"`
+}
+
+// Versioned payload with a schema version, bump the version when the payload change incompatibly and keep
+// a decoder of the previous version on the consumer until no message of that version is in flight
+type Versioned interface {
+	SchemaVersion() int
+}
+
+// Message payload already encoded with its envelope, e.g. stored by the outbox. Field of the envelope
+// left empty is filled on publish
+type Message struct {
+	Envelope Envelope
+	Body     []byte
+}
+
+// Decoders decode the body of every supported schema version into the payload of the handler
+type Decoders[T any] map[int]func(body []byte) (T, error)
+
+type envelopeContextKey struct{}
+
+// WithEnvelope keep the envelope of the consumed message, message published with the context is caused by it
+func WithEnvelope(ctx context.Context, envelope Envelope) context.Context {
+	return context.WithValue(ctx, envelopeContextKey{}, envelope)
+}
+
+// EnvelopeFromContext envelope of the message being consumed
+func EnvelopeFromContext(ctx context.Context) (Envelope, bool) {
+	envelope, ok := ctx.Value(envelopeContextKey{}).(Envelope)
+	return envelope, ok
+}
+
+// SchemaVersion schema version of the payload
+func SchemaVersion(payload interface{}) int {
+	if v, ok := payload.(Versioned); ok {
+		return v.SchemaVersion()
+	}
+
+	return defaultSchemaVersion
+}
+
+// NewMessage encode the payload with the envelope of the context, see Envelope
+func NewMessage(ctx context.Context, producer, eventType string, payload interface{}) (Message, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Message{}, err
+	}
+
+	envelope := Envelope{SchemaVersion: SchemaVersion(payload)}
+
+	return Message{
+		Envelope: envelope.complete(ctx, producer, eventType),
+		Body:     body,
+	}, nil
+}
+
+// DecodeJSON decoder of a payload that is the json of T
+func DecodeJSON[T any](body []byte) (T, error) {
+	var payload T
+
+	err := json.Unmarshal(body, &payload)
+
+	return payload, err
+}
+
+// EnvelopeOf envelope of the delivery, a message published before the envelope is version 1
+func EnvelopeOf(d amqp.Delivery) Envelope {
+	envelope := Envelope{
+		EventType:     headerString(d.Headers, HeaderEventType),
+		SchemaVersion: headerInt(d.Headers[HeaderSchemaVersion]),
+		Producer:      headerString(d.Headers, HeaderProducer),
+		CorrelationId: headerString(d.Headers, HeaderCorrelationId),
+		CausationId:   headerString(d.Headers, HeaderCausationId),
+		TraceParent:   headerString(d.Headers, HeaderTraceParent),
+		TraceState:    headerString(d.Headers, HeaderTraceState),
+		ProducedAt:    d.Timestamp,
+		MessageId:     d.MessageId,
+	}
+
+	if envelope.EventType == "" {
+		envelope.EventType = d.Type
+	}
+	if envelope.SchemaVersion == 0 {
+		envelope.SchemaVersion = defaultSchemaVersion
+	}
+	if producedAt, err := time.Parse(time.RFC3339Nano, headerString(d.Headers, HeaderProducedAt)); err == nil {
+		envelope.ProducedAt = producedAt
+	}
+
+	return envelope
+}
+
+// Headers message header of the envelope, empty field is left out
+func (e Envelope) Headers() amqp.Table {
+	headers := amqp.Table{
+		HeaderSchemaVersion: int32(e.SchemaVersion),
+		HeaderProducedAt:    e.ProducedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	for k, v := range map[string]string{
+		HeaderEventType:     e.EventType,
+		HeaderProducer:      e.Producer,
+		HeaderCorrelationId: e.CorrelationId,
+		HeaderCausationId:   e.CausationId,
+		HeaderTraceParent:   e.TraceParent,
+		HeaderTraceState:    e.TraceState,
+	} {
+		if v != "" {
+			headers[k] = v
+		}
+	}
+
+	return headers
+}
+
+// complete fill the empty field, the message being consumed is the cause and share its correlation id and trace,
+// otherwise the request id is the correlation id and the trace continue the traceparent of the request if any
+func (e Envelope) complete(ctx context.Context, producer, eventType string) Envelope {
+	parent, caused := EnvelopeFromContext(ctx)
+
+	if e.EventType == "" {
+		e.EventType = eventType
+	}
+	if e.SchemaVersion == 0 {
+		e.SchemaVersion = defaultSchemaVersion
+	}
+	if e.ProducedAt.IsZero() {
+		e.ProducedAt = time.Now().UTC()
+	}
+	if e.Producer == "" {
+		e.Producer = producer
+	}
+	if e.CorrelationId == "" && caused {
+		e.CorrelationId = parent.CorrelationId
+	}
+	if e.CorrelationId == "" {
+		_, requestId := helper.GetRequestIDContext(ctx)
+		e.CorrelationId, _ = requestId.(string)
+	}
+	if e.CausationId == "" && caused {
+		e.CausationId = parent.MessageId
+	}
+	if e.TraceParent == "" {
+		traceParent, _ := ctx.Value(helper.TraceParentContextKey).(string)
+		if caused {
+			traceParent = parent.TraceParent
+			e.TraceState = parent.TraceState
+		}
+		e.TraceParent = childTraceParent(traceParent)
+	}
+
+	return e
+}
+
+// newPublishing publish the payload with its envelope, the message id is the request id of the context
+func newPublishing(ctx context.Context, producer, eventType string, body interface{}) (amqp.Publishing, error) {
+	message, ok := body.(Message)
+	if !ok {
+		var err error
+
+		message, err = NewMessage(ctx, producer, eventType, body)
+		if err != nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while parse data : %s", err.Error())
+			return amqp.Publishing{}, err
+		}
+	}
+
+	envelope := message.Envelope.complete(ctx, producer, eventType)
+
+	_, msgId := helper.GetRequestIDContext(ctx)
+	messageId, _ := msgId.(string)
+
+	return amqp.Publishing{
+		Headers:      envelope.Headers(),
+		DeliveryMode: amqp.Persistent,
+		ContentType:  "application/json",
+		Type:         eventType,
+		Body:         message.Body,
+		MessageId:    messageId,
+		Timestamp:    envelope.ProducedAt,
+	}, nil
+}
+
+// decodePayload decode the body with the decoder of its schema version, a version without decoder is permanent
+func decodePayload[T any](envelope Envelope, body []byte, decoders Decoders[T]) (T, error) {
+	decode, ok := decoders[envelope.SchemaVersion]
+	if !ok {
+		var payload T
+		return payload, Permanent(fmt.Errorf("%w %d of %s", ErrUnknownSchemaVersion, envelope.SchemaVersion, envelope.EventType))
+	}
+
+	payload, err := decode(body)
+	if err != nil {
+		return payload, Permanent(err)
+	}
+
+	return payload, nil
+}
+
+// childTraceParent new span of the trace of the parent, a new trace is started when the parent is not valid
+func childTraceParent(parent string) string {
+	traceId := randomHex(16)
+	flags := "01"
+
+	part := strings.Split(parent, "-")
+	if len(part) == 4 && len(part[1]) == 32 && len(part[2]) == 16 && len(part[3]) == 2 {
+		traceId = part[1]
+		flags = part[3]
+	}
+
+	return fmt.Sprintf("00-%s-%s-%s", traceId, randomHex(8), flags)
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func headerString(headers amqp.Table, key string) string {
+	v, _ := headers[key].(string)
+	return v
+}
+
+func headerInt(value interface{}) int {
+	switch v := value.(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		// header decoded from json
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,414 @@
+package rabbitmq
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	memoryOnce   sync.Once
+	memoryBroker *MemoryBroker
+)
+
+// MemoryBroker in process broker for test and local run without RabbitMQ. Queue, ack, nack with requeue,
+// ttl retry queue, dead letter queue and the topology routing behave like the broker, message is lost when
+// the process exit
+type MemoryBroker struct {
+	producer    string
+	concurrency int
+	topology    Topology
+	queues      map[string]*memoryQueue
+	mutex       sync.Mutex
+	closed      bool
+}
+
+// NewMemoryBroker return the broker shared by every injector of the process, so message published by one
+// is consumed by the other
+func NewMemoryBroker(param RabbitMQParam) IRabbitMQ {
+	memoryOnce.Do(func() {
+		memoryBroker = &MemoryBroker{
+			producer:    param.Producer,
+			concurrency: param.Concurrency,
+			topology:    param.Topology,
+			queues:      map[string]*memoryQueue{},
+		}
+	})
+
+	return memoryBroker
+}
+
+// Connect nothing to connect, reopen the broker after Close
+func (mb *MemoryBroker) Connect(ctx context.Context, url string) error {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	mb.closed = false
+
+	return nil
+}
+
+// ConsumeMessage run the consumer until the context is done, every worker get one message at a time. The delivery
+// still unacked once the workers exit is requeued as redelivered, like the broker does when the channel is closed
+func (mb *MemoryBroker) ConsumeMessage(ctx context.Context, appName, consumerTag string, consumer Consumer) error {
+	workCtx := lifecycle.WithoutCancel(ctx)
+	consumerTag = fmt.Sprintf("%s|%s|%s", appName, consumer.Queue, consumerTag)
+	concurrency := consumer.Options.concurrency(mb.concurrency)
+	limiter := consumer.Options.limiter()
+	queue := mb.queue(consumer.Queue)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				message, ok := queue.get(ctx.Done(), consumerTag)
+				if !ok {
+					return
+				}
+				if limiter != nil {
+					<-limiter.C
+				}
+				handleMessage(workCtx, mb, consumer, message)
+			}
+		}()
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("Consumer %s already started", consumerTag)
+
+	// Wait for exit signal
+	<-ctx.Done()
+	log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Got exit signal")
+
+	wg.Wait()
+	if limiter != nil {
+		limiter.Stop()
+	}
+
+	if requeued := queue.release(consumerTag); requeued > 0 {
+		log.WithField(helper.GetRequestIDContext(ctx)).Infof("Consumer %s requeued %d unacked message", consumerTag, requeued)
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Stopped receiving message from queue")
+
+	return nil
+}
+
+func (mb *MemoryBroker) PublishMessage(ctx context.Context, queue string, body interface{}) error {
+	message, err := newPublishing(ctx, mb.producer, queue, body)
+	if err != nil {
+		return err
+	}
+
+	return mb.push(ctx, queue, "", queue, message)
+}
+
+// PublishEvent route the event with the topology binding of the exchange, an event without matching binding is unroutable
+func (mb *MemoryBroker) PublishEvent(ctx context.Context, exchange, routingKey string, body interface{}) error {
+	message, err := newPublishing(ctx, mb.producer, routingKey, body)
+	if err != nil {
+		return err
+	}
+
+	queues := mb.topology.route(exchange, routingKey)
+	if len(queues) == 0 {
+		return &UnroutableError{
+			Exchange:   exchange,
+			RoutingKey: routingKey,
+			ReplyCode:  amqp.NoRoute,
+			ReplyText:  "NO_ROUTE",
+		}
+	}
+
+	for _, queue := range queues {
+		err = mb.push(ctx, queue, exchange, routingKey, message)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("success send event %s : %s", routingKey, message.Body)
+
+	return nil
+}
+
+// PublishMessageToDeathLetter deliver the message to the queue again once the ttl (in millisecond) has expired
+func (mb *MemoryBroker) PublishMessageToDeathLetter(ctx context.Context, queue string, message amqp.Publishing, ttl int) error {
+	if mb.isClosed() {
+		return ErrChannelClosed
+	}
+
+	time.AfterFunc(time.Duration(ttl)*time.Millisecond, func() {
+		err := mb.push(ctx, queue, fmt.Sprintf("%s.%s", queue, "retry"), queue, message)
+		if err != nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Errorf("message id %s is lost from the retry queue of %s : %s", message.MessageId, queue, err)
+		}
+	})
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("success send message id %s to %s", message.MessageId, fmt.Sprintf("%s.%ds.%s", queue, ttl/1000, "retry"))
+
+	return nil
+}
+
+func (mb *MemoryBroker) PublishMessageToDLQ(ctx context.Context, queue string, message amqp.Publishing) error {
+	return mb.push(ctx, DeadLetterQueue(queue), "", DeadLetterQueue(queue), message)
+}
+
+func (mb *MemoryBroker) ListDeadLetter(ctx context.Context, queue string, limit int) ([]DeadLetterMessage, error) {
+	message := []DeadLetterMessage{}
+
+	for _, d := range mb.queue(DeadLetterQueue(queue)).peek(limit) {
+		message = append(message, newDeadLetterMessage(queue, d))
+	}
+
+	return message, nil
+}
+
+func (mb *MemoryBroker) ReplayDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error) {
+	removed := mb.queue(DeadLetterQueue(queue)).remove(selectMessage(messageIds))
+
+	for i, d := range removed {
+		original, message := replayMessage(queue, d)
+
+		err := mb.push(ctx, original, "", original, message)
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(removed), nil
+}
+
+func (mb *MemoryBroker) PurgeDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error) {
+	return len(mb.queue(DeadLetterQueue(queue)).remove(selectMessage(messageIds))), nil
+}
+
+// Close stop accepting message, queued message is kept for the next Connect
+func (mb *MemoryBroker) Close(ctx context.Context) error {
+	log.WithField(helper.GetRequestIDContext(ctx)).Info("close a connection")
+
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	mb.closed = true
+
+	return nil
+}
+
+func (mb *MemoryBroker) isClosed() bool {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	return mb.closed
+}
+
+// queue declare the queue on first use
+func (mb *MemoryBroker) queue(name string) *memoryQueue {
+	mb.mutex.Lock()
+	defer mb.mutex.Unlock()
+
+	q, ok := mb.queues[name]
+	if !ok {
+		q = newMemoryQueue()
+		mb.queues[name] = q
+	}
+
+	return q
+}
+
+func (mb *MemoryBroker) push(ctx context.Context, queue, exchange, routingKey string, message amqp.Publishing) error {
+	if mb.isClosed() {
+		return ErrChannelClosed
+	}
+
+	mb.queue(queue).push(exchange, routingKey, message)
+
+	return nil
+}
+
+// memoryQueue ready message in order and the delivered message waiting for ack, it is the Acknowledger of its delivery
+type memoryQueue struct {
+	mutex   sync.Mutex
+	tag     uint64
+	ready   []amqp.Delivery
+	unacked map[uint64]amqp.Delivery
+	// closed and replaced on every push to wake up the waiting worker
+	notify chan struct{}
+}
+
+func newMemoryQueue() *memoryQueue {
+	return &memoryQueue{
+		unacked: map[uint64]amqp.Delivery{},
+		notify:  make(chan struct{}),
+	}
+}
+
+func (q *memoryQueue) push(exchange, routingKey string, message amqp.Publishing) {
+	q.requeue(amqp.Delivery{
+		Headers:      message.Headers,
+		ContentType:  message.ContentType,
+		DeliveryMode: message.DeliveryMode,
+		MessageId:    message.MessageId,
+		Timestamp:    message.Timestamp,
+		Type:         message.Type,
+		Body:         message.Body,
+		Exchange:     exchange,
+		RoutingKey:   routingKey,
+	})
+}
+
+func (q *memoryQueue) requeue(d amqp.Delivery) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.ready = append(q.ready, d)
+
+	close(q.notify)
+	q.notify = make(chan struct{})
+}
+
+// get wait for the next message until stop, the message stay unacked until it is acknowledged
+func (q *memoryQueue) get(stop <-chan struct{}, consumerTag string) (amqp.Delivery, bool) {
+	for {
+		q.mutex.Lock()
+		if len(q.ready) > 0 {
+			d := q.ready[0]
+			q.ready = q.ready[1:]
+
+			q.tag++
+			d.Acknowledger = q
+			d.DeliveryTag = q.tag
+			d.ConsumerTag = consumerTag
+			q.unacked[d.DeliveryTag] = d
+
+			q.mutex.Unlock()
+			return d, true
+		}
+		notify := q.notify
+		q.mutex.Unlock()
+
+		select {
+		case <-stop:
+			return amqp.Delivery{}, false
+		case <-notify:
+		}
+	}
+}
+
+// release requeue the unacked delivery of the consumer in front of the ready message, in delivery order
+func (q *memoryQueue) release(consumerTag string) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	released := []amqp.Delivery{}
+	for tag, d := range q.unacked {
+		if d.ConsumerTag == consumerTag {
+			released = append(released, d)
+			delete(q.unacked, tag)
+		}
+	}
+	if len(released) == 0 {
+		return 0
+	}
+
+	sort.Slice(released, func(i, j int) bool {
+		return released[i].DeliveryTag < released[j].DeliveryTag
+	})
+	for i := range released {
+		released[i].Redelivered = true
+		released[i].Acknowledger = nil
+	}
+	q.ready = append(released, q.ready...)
+
+	close(q.notify)
+	q.notify = make(chan struct{})
+
+	return len(released)
+}
+
+// peek ready message without delivering it
+func (q *memoryQueue) peek(limit int) []amqp.Delivery {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	count := len(q.ready)
+	if limit > 0 && limit < count {
+		count = limit
+	}
+
+	message := make([]amqp.Delivery, count)
+	copy(message, q.ready[:count])
+
+	return message
+}
+
+// remove take the selected ready message out of the queue
+func (q *memoryQueue) remove(selected func(messageId string) bool) []amqp.Delivery {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	removed := []amqp.Delivery{}
+	kept := make([]amqp.Delivery, 0, len(q.ready))
+	for _, d := range q.ready {
+		if selected(d.MessageId) {
+			removed = append(removed, d)
+		} else {
+			kept = append(kept, d)
+		}
+	}
+	q.ready = kept
+
+	return removed
+}
+
+func (q *memoryQueue) Ack(tag uint64, multiple bool) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.settle(tag, multiple)
+
+	return nil
+}
+
+func (q *memoryQueue) Nack(tag uint64, multiple bool, requeue bool) error {
+	q.mutex.Lock()
+	settled := q.settle(tag, multiple)
+	q.mutex.Unlock()
+
+	if requeue {
+		for _, d := range settled {
+			d.Redelivered = true
+			d.Acknowledger = nil
+			q.requeue(d)
+		}
+	}
+
+	return nil
+}
+
+func (q *memoryQueue) Reject(tag uint64, requeue bool) error {
+	return q.Nack(tag, false, requeue)
+}
+
+// settle remove the delivery up to the tag from unacked, the caller must hold the mutex
+func (q *memoryQueue) settle(tag uint64, multiple bool) []amqp.Delivery {
+	settled := []amqp.Delivery{}
+
+	for t, d := range q.unacked {
+		if t == tag || (multiple && t < tag) {
+			settled = append(settled, d)
+			delete(q.unacked, t)
+		}
+	}
+
+	return settled
+}
@@ -0,0 +1,217 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const testQueue = "test.queue"
+
+var errWorker = errors.New("worker failed")
+
+func newTestBroker() *MemoryBroker {
+	return &MemoryBroker{
+		producer:    "test",
+		concurrency: 1,
+		queues:      map[string]*memoryQueue{},
+	}
+}
+
+func publishTest(t *testing.T, mb *MemoryBroker) {
+	t.Helper()
+
+	ctx := helper.SetRequestIDToContext(context.Background(), "message-1")
+	err := mb.PublishMessage(ctx, testQueue, map[string]string{"id": "1"})
+	if err != nil {
+		t.Fatalf("publish: %s", err)
+	}
+}
+
+// nextMessage wait for the next delivery of the queue
+func nextMessage(t *testing.T, q *memoryQueue) amqp.Delivery {
+	t.Helper()
+
+	stop := make(chan struct{})
+	timer := time.AfterFunc(time.Second, func() { close(stop) })
+	defer timer.Stop()
+
+	d, ok := q.get(stop, "test")
+	if !ok {
+		t.Fatal("no message delivered")
+	}
+
+	return d
+}
+
+func pending(q *memoryQueue) (ready, unacked int) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return len(q.ready), len(q.unacked)
+}
+
+func TestMemoryBrokerHandleMessage(t *testing.T) {
+	retry := RetryPolicy{MaxAttempt: 3, Backoff: []time.Duration{10 * time.Millisecond}}
+
+	tests := []struct {
+		name        string
+		err         error
+		closed      bool
+		wantAttempt int
+		// redelivered the message is requeued by the nack, otherwise it is republished through the retry queue
+		wantRedelivered bool
+		wantRetry       bool
+	}{
+		{name: "ack"},
+		{name: "retry through the ttl retry queue", err: errWorker, wantAttempt: 1, wantRetry: true},
+		{name: "requeue without using up an attempt", err: Requeue(errWorker), wantRetry: true},
+		{name: "nack with requeue when the retry can not be published", err: errWorker, closed: true, wantRedelivered: true, wantRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mb := newTestBroker()
+			queue := mb.queue(testQueue)
+			publishTest(t, mb)
+
+			d := nextMessage(t, queue)
+			if tt.closed {
+				mb.Close(context.Background())
+			}
+
+			consumer := Consumer{
+				Queue:   testQueue,
+				Options: ConsumerOptions{Retry: retry},
+				Worker: func(ctx context.Context, d amqp.Delivery) error {
+					return tt.err
+				},
+			}
+			handleMessage(context.Background(), mb, consumer, d)
+
+			if _, unacked := pending(queue); unacked != 0 {
+				t.Errorf("unacked = %d, want 0", unacked)
+			}
+
+			if !tt.wantRetry {
+				if ready, _ := pending(queue); ready != 0 {
+					t.Errorf("ready = %d, want 0", ready)
+				}
+				return
+			}
+
+			retried := nextMessage(t, queue)
+			if retried.MessageId != d.MessageId {
+				t.Errorf("message id = %s, want %s", retried.MessageId, d.MessageId)
+			}
+			if retried.Redelivered != tt.wantRedelivered {
+				t.Errorf("redelivered = %t, want %t", retried.Redelivered, tt.wantRedelivered)
+			}
+			if attempt := Attempt(retried); attempt != tt.wantAttempt {
+				t.Errorf("attempt = %d, want %d", attempt, tt.wantAttempt)
+			}
+		})
+	}
+}
+
+func TestMemoryBrokerDeadLetter(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int32
+	}{
+		{name: "after max attempt", err: errWorker, wantCalls: 3},
+		{name: "permanent error without retry", err: Permanent(errWorker), wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mb := newTestBroker()
+			publishTest(t, mb)
+
+			var calls int32
+			consumer := Consumer{
+				Queue: testQueue,
+				Options: ConsumerOptions{
+					Retry: RetryPolicy{MaxAttempt: 3, Backoff: []time.Duration{time.Millisecond}},
+				},
+				Worker: func(ctx context.Context, d amqp.Delivery) error {
+					atomic.AddInt32(&calls, 1)
+					return tt.err
+				},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				mb.ConsumeMessage(ctx, "test", "test", consumer)
+			}()
+
+			var dead []DeadLetterMessage
+			deadline := time.Now().Add(time.Second)
+			for len(dead) == 0 && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+				dead, _ = mb.ListDeadLetter(context.Background(), testQueue, 0)
+			}
+
+			cancel()
+			<-done
+
+			if len(dead) != 1 {
+				t.Fatalf("dead letter = %d, want 1", len(dead))
+			}
+			if got := atomic.LoadInt32(&calls); got != tt.wantCalls {
+				t.Errorf("worker calls = %d, want %d", got, tt.wantCalls)
+			}
+
+			d := mb.queue(DeadLetterQueue(testQueue)).peek(1)[0]
+			if attempt := Attempt(d); attempt != int(tt.wantCalls) {
+				t.Errorf("attempt = %d, want %d", attempt, tt.wantCalls)
+			}
+			if reason := d.Headers[HeaderFailureReason]; reason != errWorker.Error() {
+				t.Errorf("failure reason = %v, want %s", reason, errWorker)
+			}
+			if original := d.Headers[HeaderOriginalQueue]; original != testQueue {
+				t.Errorf("original queue = %v, want %s", original, testQueue)
+			}
+		})
+	}
+}
+
+func TestMemoryQueueRelease(t *testing.T) {
+	mb := newTestBroker()
+	queue := mb.queue(testQueue)
+	publishTest(t, mb)
+	publishTest(t, mb)
+
+	nextMessage(t, queue)
+	nextMessage(t, queue)
+	publishTest(t, mb)
+
+	if released := queue.release("other"); released != 0 {
+		t.Errorf("released of other consumer = %d, want 0", released)
+	}
+	if released := queue.release("test"); released != 2 {
+		t.Fatalf("released = %d, want 2", released)
+	}
+
+	ready, unacked := pending(queue)
+	if ready != 3 || unacked != 0 {
+		t.Fatalf("ready/unacked = %d/%d, want 3/0", ready, unacked)
+	}
+
+	// the released delivery come before the message published after it
+	for i, want := range []bool{true, true, false} {
+		d := nextMessage(t, queue)
+		if d.Redelivered != want {
+			t.Errorf("message %d redelivered = %t, want %t", i, d.Redelivered, want)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+var (
+	// ErrPublishNacked the broker could not take the message, e.g. the queue is full or an internal error
+	ErrPublishNacked = errors.New("message is nacked by the broker")
+	// ErrConfirmTimeout the broker did not confirm the message in time, the message may or may not be stored
+	ErrConfirmTimeout = errors.New("timeout waiting for the broker confirm")
+	// ErrChannelClosed the channel was closed before the message is confirmed
+	ErrChannelClosed = errors.New("channel closed before the broker confirm")
+)
+
+// defaultConfirmTimeout used when RABBITMQ_PUBLISH_CONFIRM_TIMEOUT is not set
+const defaultConfirmTimeout = 5 * time.Second
+
+// UnroutableError mandatory message returned by the broker because no queue is bound to the routing key
+type UnroutableError struct {
+	Exchange   string
+	RoutingKey string
+	ReplyCode  uint16
+	ReplyText  string
+}
+
+func (e *UnroutableError) Error() string {
+	return fmt.Sprintf("message to exchange %q with routing key %q is returned : %d %s", e.Exchange, e.RoutingKey, e.ReplyCode, e.ReplyText)
+}
+
+func IsUnroutable(err error) bool {
+	var unroutable *UnroutableError
+	return errors.As(err, &unroutable)
+}
+
+// confirmChannel channel in confirm mode, a message is published one at a time so the return received
+// before the confirm belongs to the message being published
+type confirmChannel struct {
+	*amqp.Channel
+	returns chan amqp.Return
+}
+
+func newConfirmChannel(connection *amqp.Connection) (*confirmChannel, error) {
+	channel, err := connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	err = channel.Confirm(false)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	return &confirmChannel{
+		Channel: channel,
+		returns: channel.NotifyReturn(make(chan amqp.Return, 1)),
+	}, nil
+}
+
+// publish publish the message as mandatory and wait for the broker confirm. On timeout the channel is closed
+// so a late confirm or return is not mistaken for the next message
+func (ch *confirmChannel) publish(ctx context.Context, exchange, key string, message amqp.Publishing, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = defaultConfirmTimeout
+	}
+
+	confirm, err := ch.PublishWithDeferredConfirmWithContext(
+		ctx,
+		exchange, // exchange
+		key,      // routing key
+		true,     // mandatory
+		false,
+		message)
+	if err != nil {
+		return err
+	}
+
+	confirmCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	acked, err := confirm.WaitContext(confirmCtx)
+	if err != nil {
+		ch.Close()
+
+		if errors.Is(err, context.DeadlineExceeded) {
+			return fmt.Errorf("%w after %s", ErrConfirmTimeout, timeout)
+		}
+		return err
+	}
+
+	// the return is dispatched before the ack of the same message
+	select {
+	case returned, ok := <-ch.returns:
+		if ok {
+			return &UnroutableError{
+				Exchange:   returned.Exchange,
+				RoutingKey: returned.RoutingKey,
+				ReplyCode:  returned.ReplyCode,
+				ReplyText:  returned.ReplyText,
+			}
+		}
+	default:
+	}
+
+	if !acked {
+		if ch.IsClosed() {
+			return ErrChannelClosed
+		}
+		return ErrPublishNacked
+	}
+
+	return nil
+}
@@ -2,9 +2,9 @@ package rabbitmq
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/galihfebrizki/dbo-api/helper"
 
@@ -14,155 +14,136 @@ import (
 
 type IRabbitMQ interface {
 	Connect(ctx context.Context, url string) error
-	ConsumeMessage(ctx context.Context, appName, queueName, consumerTag string, worker func(ctx context.Context, d amqp.Delivery) bool, requeue bool) error
+	// ConsumeMessage consume the queue until the context is done, it return once the message in process is done
+	ConsumeMessage(ctx context.Context, appName, consumerTag string, consumer Consumer) error
 	PublishMessage(ctx context.Context, queue string, body interface{}) error
-	PublishMessageToDeathLetter(ctx context.Context, queue string, body interface{}, ttl int) error
+	PublishEvent(ctx context.Context, exchange, routingKey string, body interface{}) error
+	PublishMessageToDeathLetter(ctx context.Context, queue string, message amqp.Publishing, ttl int) error
+	PublishMessageToDLQ(ctx context.Context, queue string, message amqp.Publishing) error
+	ListDeadLetter(ctx context.Context, queue string, limit int) ([]DeadLetterMessage, error)
+	ReplayDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error)
+	PurgeDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error)
 	Close(ctx context.Context) error
 }
 
 type RabbitMQ struct {
-	url         string
-	concurrency int
-	connection  *amqp.Connection
-	channel     *amqp.Channel
-	mutex       sync.Mutex
-	once        sync.Once
+	url          string
+	producer     string
+	concurrency  int
+	reconnectMin time.Duration
+	reconnectMax time.Duration
+	// confirmTimeout wait for the broker confirm of a published message
+	confirmTimeout time.Duration
+	topology       Topology
+	connection     *amqp.Connection
+	channel        *confirmChannel
+	mutex          sync.Mutex
+	closed         bool
 }
 
+// Broker driver
+const (
+	DriverRabbitMQ = "rabbitmq"
+	DriverMemory   = "memory"
+	DriverRedis    = "redis"
+)
+
 type RabbitMQParam struct {
-	Url         string
-	Concurrency int
+	// Driver rabbitmq, memory or redis, see NewMemoryBroker and NewStreamBroker
+	Driver string
+	Url    string
+	// Producer app name in the envelope of published message
+	Producer       string
+	Concurrency    int
+	ReconnectMin   time.Duration
+	ReconnectMax   time.Duration
+	ConfirmTimeout time.Duration
+	Topology       Topology
+	// RedisAddress and ClaimIdle only used by the redis driver
+	RedisAddress string
+	ClaimIdle    time.Duration
 }
 
 func NewRabbitMQConn(param RabbitMQParam) IRabbitMQ {
-	conn, err := amqp.Dial(param.Url)
-	if err != nil {
-		log.Fatalf("failed to connect to RabbitMQ Datasource. URL: %s, Error: %s", param.Url, err.Error())
+	switch param.Driver {
+	case DriverMemory:
+		log.Warn("using the in memory message broker, message is lost when the process exit")
+		return NewMemoryBroker(param)
+	case DriverRedis:
+		return NewStreamBroker(param)
 	}
 
-	channel, err := conn.Channel()
-	if err != nil {
-		log.Fatalf("failed to open channel : %s", err.Error())
+	mq := &RabbitMQ{
+		url:            param.Url,
+		producer:       param.Producer,
+		concurrency:    param.Concurrency,
+		reconnectMin:   param.ReconnectMin,
+		reconnectMax:   param.ReconnectMax,
+		confirmTimeout: param.ConfirmTimeout,
+		topology:       param.Topology,
+		mutex:          sync.Mutex{},
 	}
 
-	return &RabbitMQ{
-		url:         param.Url,
-		concurrency: param.Concurrency,
-		connection:  conn,
-		channel:     channel,
-		mutex:       sync.Mutex{},
-		once:        sync.Once{},
+	err := mq.Connect(context.Background(), param.Url)
+	if err != nil {
+		log.Fatalf("failed to connect to RabbitMQ Datasource. URL: %s, Error: %s", param.Url, err.Error())
 	}
+
+	return mq
 }
 
+// Connect dial a new connection, declare the topology and open the publish channel in confirm mode, the caller must hold the mutex.
+// The connection is watched and reopened in the background when the broker or the network close it
 func (mq *RabbitMQ) Connect(ctx context.Context, url string) error {
-	var err error
-
 	log.WithField(helper.GetRequestIDContext(ctx)).Info("create a connection to url : ", url)
 
-	mq.connection, err = amqp.Dial(url)
+	connection, err := amqp.Dial(url)
 	if err != nil {
 		log.WithField(helper.GetRequestIDContext(ctx)).Info("failed to create a connection to url : ", url)
 		return err
 	}
 
-	mq.channel, err = mq.connection.Channel()
+	channel, err := newConfirmChannel(connection)
 	if err != nil {
 		log.WithField(helper.GetRequestIDContext(ctx)).Info("create a channel connection to url : ", url)
+		connection.Close()
 		return err
 	}
 
-	return nil
-}
+	err = mq.topology.declare(channel.Channel)
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("failed to declare the topology : %s", err.Error())
+		connection.Close()
+		return err
+	}
 
-func (mq *RabbitMQ) ConsumeMessage(ctx context.Context, appName, queueName, consumerTag string, worker func(ctx context.Context, d amqp.Delivery) bool, requeue bool) error {
+	mq.connection = connection
+	mq.channel = channel
 
-	mq.once.Do(func() {
-		mq.mutex.Lock()
-		helper.ConsumerCount++
-		mq.mutex.Unlock()
-	})
+	go mq.watchConnection(ctx, connection, connection.NotifyClose(make(chan *amqp.Error, 1)))
 
+	return nil
+}
+
+// ensureConnection reopen the connection or the publish channel when it has been closed, the caller must hold the mutex
+func (mq *RabbitMQ) ensureConnection(ctx context.Context) error {
 	if mq.connection == nil || mq.connection.IsClosed() {
 		err := mq.Connect(ctx, mq.url)
 		if err != nil {
 			log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to reconnect a connection")
+			return err
 		}
 	}
 
-	//QueueDeclare declares a queue to hold messages and deliver to consumers. Declaring creates a queue if it doesn't already exist, or ensures that an existing queue matches the same parameters.
-	queue, err := mq.channel.QueueDeclare(
-		queueName, // name
-		true,      // durable
-		false,     // delete when unused
-		false,     // exclusive
-		false,     // no-wait
-		nil,       // arguments
-	)
-	if err != nil {
-		log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to declare a queue")
-		return err
-	}
-
-	//Qos controls how many messages or how many bytes the server will try to keep on the network for consumers before receiving delivery acks. The intent of Qos is to make sure the network buffers stay full between the server and client.
-	prefetchCount := mq.concurrency
-	err = mq.channel.Qos(
-		prefetchCount, // prefetch count
-		0,             // prefetch size
-		false,         // global
-	)
-	if err != nil {
-		log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to set QOS")
-		return err
-	}
-
-	consumerTag = fmt.Sprintf("%s|%s|%s", appName, queueName, consumerTag)
-
-	messages, err := mq.channel.Consume(
-		queue.Name,  // queue
-		consumerTag, // consumer
-		false,       // auto-ack
-		false,       // exclusive
-		false,       // no-local
-		false,       // no-wait
-		nil,         // args
-	)
-	if err != nil {
-		log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to register a consumer")
-		return err
-	}
-
-	for i := 0; i < mq.concurrency; i++ {
-		go func(_ctx context.Context, _messages <-chan amqp.Delivery, _done chan bool) {
-			for message := range _messages {
-				log.WithField(helper.GetRequestIDContext(_ctx)).Infof("consumer %s consume message id %s with body : %v", message.ConsumerTag, message.MessageId, string(message.Body))
-				if worker(_ctx, message) {
-					log.WithField(helper.GetRequestIDContext(_ctx)).Infof("consumer %s ack message id %s with body : %v", message.ConsumerTag, message.MessageId, string(message.Body))
-					message.Ack(false)
-				} else {
-					log.WithField(helper.GetRequestIDContext(_ctx)).Infof("consumer %s nack message id %s with body : %v", message.ConsumerTag, message.MessageId, string(message.Body))
-					message.Nack(false, requeue)
-				}
-			}
-			_done <- true
-		}(ctx, messages, helper.ExitConcurrency)
-	}
-
-	log.WithField(helper.GetRequestIDContext(ctx)).Infof("Consumer %s already started", consumerTag)
-
-	// Wait for exit signal
-	<-helper.ExitConsumer
-	log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Got exit signal")
-
-	// Stop receiving message from queue
-	err = mq.channel.Cancel(consumerTag, false)
-	if err != nil {
-		log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to cancel the consumer")
-		return err
+	if mq.channel == nil || mq.channel.IsClosed() {
+		channel, err := newConfirmChannel(mq.connection)
+		if err != nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to reopen the channel")
+			return err
+		}
+		mq.channel = channel
 	}
 
-	log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Stopped receiving message from queue")
-
 	return nil
 }
 
@@ -171,17 +152,14 @@ func (mq *RabbitMQ) PublishMessage(ctx context.Context, queue string, body inter
 	mq.mutex.Lock()
 	defer mq.mutex.Unlock()
 
-	if mq.connection == nil || mq.connection.IsClosed() {
-		err := mq.Connect(ctx, mq.url)
-		if err != nil {
-			log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to reconnect a connection")
-		}
+	err := mq.ensureConnection(ctx)
+	if err != nil {
+		return err
 	}
 
-	// parse data
-	dataParse, err := json.Marshal(body)
+	message, err := newPublishing(ctx, mq.producer, queue, body)
 	if err != nil {
-		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while parse data : %s", err.Error())
+		return err
 	}
 
 	q, err := mq.channel.QueueDeclare(
@@ -197,21 +175,8 @@ func (mq *RabbitMQ) PublishMessage(ctx context.Context, queue string, body inter
 		return err
 	}
 
-	_, msgId := helper.GetRequestIDContext(ctx)
-
-	// publish data
-	err = mq.channel.PublishWithContext(
-		ctx,
-		"",     // exchange
-		q.Name, // routing key
-		false,  // mandatory
-		false,
-		amqp.Publishing{
-			DeliveryMode: amqp.Persistent,
-			ContentType:  "application/json",
-			Body:         []byte(dataParse),
-			MessageId:    msgId.(string),
-		})
+	// publish data and wait until the broker has taken it
+	err = mq.channel.publish(ctx, "", q.Name, message, mq.confirmTimeout)
 
 	if err != nil {
 		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while publish data : %s", err.Error())
@@ -223,22 +188,43 @@ func (mq *RabbitMQ) PublishMessage(ctx context.Context, queue string, body inter
 	return nil
 }
 
-func (mq *RabbitMQ) PublishMessageToDeathLetter(ctx context.Context, queue string, body interface{}, ttl int) error {
+// PublishEvent publish the event to the exchange by routing key, every queue bound with a matching pattern get a copy.
+// An event without any matching binding is returned as unroutable
+func (mq *RabbitMQ) PublishEvent(ctx context.Context, exchange, routingKey string, body interface{}) error {
 	mq.mutex.Lock()
 	defer mq.mutex.Unlock()
 
-	if mq.connection == nil || mq.connection.IsClosed() {
-		err := mq.Connect(ctx, mq.url)
-		if err != nil {
-			log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to reconnect a connection")
-		}
+	err := mq.ensureConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	message, err := newPublishing(ctx, mq.producer, routingKey, body)
+	if err != nil {
+		return err
 	}
-	// parse data
-	dataParse, err := json.Marshal(body)
+
+	err = mq.channel.publish(ctx, exchange, routingKey, message, mq.confirmTimeout)
 	if err != nil {
-		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while parse data : %s", err.Error())
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while publish event %s : %s", routingKey, err.Error())
+		return err
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("success send event %s : %s", routingKey, message.Body)
+
+	return nil
+}
+
+// PublishMessageToDeathLetter publish the message into ttl queue of the queue, the message is routed back to the queue
+// once the ttl (in millisecond) has expired
+func (mq *RabbitMQ) PublishMessageToDeathLetter(ctx context.Context, queue string, message amqp.Publishing, ttl int) error {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+
+	err := mq.ensureConnection(ctx)
+	if err != nil {
+		return err
 	}
-	_, msgId := helper.GetRequestIDContext(ctx)
 
 	exchangeName := fmt.Sprintf("%s.%s", queue, "retry")
 	err = mq.channel.ExchangeDeclare(
@@ -287,25 +273,49 @@ func (mq *RabbitMQ) PublishMessageToDeathLetter(ctx context.Context, queue strin
 	}
 
 	// publish data
-	err = mq.channel.PublishWithContext(
-		ctx,
-		"",               // exchange
-		queueDeathLetter, // routing key
-		false,            // mandatory
-		false,
-		amqp.Publishing{
-			DeliveryMode: amqp.Persistent,
-			ContentType:  "application/json",
-			Body:         []byte(dataParse),
-			MessageId:    msgId.(string),
-		})
+	err = mq.channel.publish(ctx, "", queueDeathLetter, message, mq.confirmTimeout)
 
 	if err != nil {
 		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while publish data : %s", err.Error())
 		return err
 	}
 
-	log.WithField(helper.GetRequestIDContext(ctx)).Infof("success send message : %s", body)
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("success send message id %s to %s", message.MessageId, queueDeathLetter)
+
+	return nil
+}
+
+// PublishMessageToDLQ park the message in the dead letter queue of the queue, the message stay there until replayed or purged
+func (mq *RabbitMQ) PublishMessageToDLQ(ctx context.Context, queue string, message amqp.Publishing) error {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+
+	err := mq.ensureConnection(ctx)
+	if err != nil {
+		return err
+	}
+
+	q, err := mq.channel.QueueDeclare(
+		DeadLetterQueue(queue), // name
+		true,                   // durable
+		false,                  // delete when unused
+		false,                  // exclusive
+		false,                  // no-wait
+		nil,                    // arguments
+	)
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while declare queue : %s", err.Error())
+		return err
+	}
+
+	err = mq.channel.publish(ctx, "", q.Name, message, mq.confirmTimeout)
+
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while publish data : %s", err.Error())
+		return err
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("success send message id %s to %s", message.MessageId, q.Name)
 
 	return nil
 }
@@ -314,6 +324,12 @@ func (mq *RabbitMQ) PublishMessageToDeathLetter(ctx context.Context, queue strin
 func (mq *RabbitMQ) Close(ctx context.Context) error {
 	log.WithField(helper.GetRequestIDContext(ctx)).Info("close a connection")
 
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+
+	// stop reconnecting once the connection is closed on purpose
+	mq.closed = true
+
 	// Close queue channel and connection
 	err := mq.channel.Close()
 	if err != nil {
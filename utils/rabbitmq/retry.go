@@ -0,0 +1,135 @@
+package rabbitmq
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// header of retried and dead lettered message
+const (
+	HeaderAttempt       = "x-attempt"
+	HeaderFailureReason = "x-failure-reason"
+	HeaderOriginalQueue = "x-original-queue"
+	HeaderFailedAt      = "x-failed-at"
+)
+
+// RetryPolicy failed delivery is republished through the ttl retry queue until MaxAttempt,
+// then it is parked in the dead letter queue. Backoff is the delay after the n-th failed attempt,
+// the last delay is used for the following attempt
+type RetryPolicy struct {
+	MaxAttempt int
+	Backoff    []time.Duration
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent mark the worker error as not retryable, the message goes to the dead letter queue directly
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	var permanent permanentError
+	return errors.As(err, &permanent)
+}
+
+type requeueError struct {
+	err error
+}
+
+func (e requeueError) Error() string {
+	return e.err.Error()
+}
+
+func (e requeueError) Unwrap() error {
+	return e.err
+}
+
+// Requeue mark the worker error as not a failed attempt, e.g. the message is still processed by another delivery.
+// The message goes back through the retry queue without using up an attempt
+func Requeue(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return requeueError{err: err}
+}
+
+func IsRequeue(err error) bool {
+	var requeue requeueError
+	return errors.As(err, &requeue)
+}
+
+// Delay wait before the next attempt after the given failed attempt
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	if len(p.Backoff) == 0 {
+		return 0
+	}
+
+	if attempt > len(p.Backoff) {
+		attempt = len(p.Backoff)
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	return p.Backoff[attempt-1]
+}
+
+// ShouldRetry the failed attempt still has another attempt left, a requeue error is always retried
+func (p RetryPolicy) ShouldRetry(attempt int, err error) bool {
+	if IsRequeue(err) {
+		return true
+	}
+
+	return !IsPermanent(err) && attempt < p.MaxAttempt
+}
+
+// ParseBackoff parse comma separated seconds, e.g. "5,30,120"
+func ParseBackoff(value string) ([]time.Duration, error) {
+	backoff := []time.Duration{}
+
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+
+		second, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+
+		backoff = append(backoff, time.Duration(second)*time.Second)
+	}
+
+	return backoff, nil
+}
+
+// Attempt number of failed attempt of the delivery
+func Attempt(d amqp.Delivery) int {
+	return headerInt(d.Headers[HeaderAttempt])
+}
+
+// DeadLetterQueue parking queue of message that failed every attempt
+func DeadLetterQueue(queue string) string {
+	return queue + ".dlq"
+}
@@ -0,0 +1,685 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
+
+	redis "github.com/go-redis/redis/v8"
+	amqp "github.com/rabbitmq/amqp091-go"
+	log "github.com/sirupsen/logrus"
+)
+
+// stream key, a queue is a stream consumed by one consumer group, retry message wait in a sorted set by due time
+const (
+	streamKey      = "stream:%s"
+	streamRetryKey = "stream:%s:retry"
+	streamGroup    = "consumer"
+	streamField    = "message"
+	// streamBlock wait for new entry, the exit signal is checked in between
+	streamBlock = 2 * time.Second
+	// defaultClaimIdle used when REDIS_STREAM_CLAIM_IDLE is not set
+	defaultClaimIdle = time.Minute
+)
+
+// moveRetryScript move due retry message to the stream atomically, so a message is never lost nor moved twice
+var moveRetryScript = redis.NewScript(`
+local due = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, message in ipairs(due) do
+	redis.call('XADD', KEYS[2], '*', 'message', message)
+	redis.call('ZREM', KEYS[1], message)
+end
+return #due
+`)
+
+// StreamBroker message transport on Redis Streams for deployment without RabbitMQ. Every queue is a stream with
+// one consumer group, entry pending on a crashed worker is claimed by another worker after the claim idle time,
+// retry and dead letter queue behave like the RabbitMQ path
+type StreamBroker struct {
+	client       *redis.Client
+	producer     string
+	concurrency  int
+	topology     Topology
+	claimIdle    time.Duration
+	reconnectMin time.Duration
+	reconnectMax time.Duration
+	mutex        sync.Mutex
+	closed       bool
+}
+
+// streamMessage stream entry of a message
+type streamMessage struct {
+	Headers     amqp.Table `json:"headers"`
+	ContentType string     `json:"content_type"`
+	MessageId   string     `json:"message_id"`
+	Type        string     `json:"type"`
+	Timestamp   time.Time  `json:"timestamp"`
+	Exchange    string     `json:"exchange"`
+	RoutingKey  string     `json:"routing_key"`
+	Redelivered bool       `json:"redelivered"`
+	Body        []byte     `json:"body"`
+}
+
+func NewStreamBroker(param RabbitMQParam) IRabbitMQ {
+	ctx := context.Background()
+
+	client := redis.NewClient(&redis.Options{
+		Addr: param.RedisAddress,
+	})
+
+	err := client.Ping(ctx).Err()
+	if err != nil {
+		log.Fatalf("failed to connect to Redis stream. Address: %s, Error: %s", param.RedisAddress, err.Error())
+	}
+
+	claimIdle := param.ClaimIdle
+	if claimIdle <= 0 {
+		claimIdle = defaultClaimIdle
+	}
+
+	return &StreamBroker{
+		client:       client,
+		producer:     param.Producer,
+		concurrency:  param.Concurrency,
+		topology:     param.Topology,
+		claimIdle:    claimIdle,
+		reconnectMin: param.ReconnectMin,
+		reconnectMax: param.ReconnectMax,
+	}
+}
+
+// Connect the client reconnect on its own, check the server is reachable and reopen the broker after Close
+func (sb *StreamBroker) Connect(ctx context.Context, url string) error {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+
+	err := sb.client.Ping(ctx).Err()
+	if err != nil {
+		return err
+	}
+
+	sb.closed = false
+
+	return nil
+}
+
+// ConsumeMessage read the stream of the queue until the context is done. Due retry message is moved to the stream and
+// entry idle on another consumer is claimed before every read, a read error is retried with jittered backoff
+func (sb *StreamBroker) ConsumeMessage(ctx context.Context, appName, consumerTag string, consumer Consumer) error {
+	workCtx := lifecycle.WithoutCancel(ctx)
+	consumerTag = fmt.Sprintf("%s|%s|%s", appName, consumer.Queue, consumerTag)
+	concurrency := consumer.Options.concurrency(sb.concurrency)
+	prefetch := consumer.Options.prefetch(concurrency)
+	limiter := consumer.Options.limiter()
+
+	messages := make(chan amqp.Delivery, prefetch)
+
+	// the reader stop with the context, the redis command of the entry being read is not cut short
+	go sb.readStream(workCtx, consumer.Queue, consumerTag, prefetch, messages, ctx.Done())
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for message := range messages {
+				if limiter != nil {
+					<-limiter.C
+				}
+				stop := sb.keepAlive(workCtx, message)
+				handleMessage(workCtx, sb, consumer, message)
+				stop()
+			}
+		}()
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("Consumer %s already started", consumerTag)
+
+	// Wait for exit signal
+	<-ctx.Done()
+	log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Got exit signal")
+
+	// the reader stop, message already read is processed before the worker stop
+	wg.Wait()
+	if limiter != nil {
+		limiter.Stop()
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Stopped receiving message from queue")
+
+	return nil
+}
+
+// readStream deliver entry of the stream to the worker until stop, the message channel is closed on return
+func (sb *StreamBroker) readStream(ctx context.Context, queue, consumerTag string, count int, messages chan<- amqp.Delivery, stop <-chan struct{}) {
+	defer close(messages)
+
+	stream := fmt.Sprintf(streamKey, queue)
+	lastClaim := time.Time{}
+
+	for attempt := 0; ; {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		entries, err := sb.readEntries(ctx, queue, consumerTag, count, &lastClaim)
+		if err != nil {
+			delay := jitterDelay(sb.reconnectMin, sb.reconnectMax, attempt)
+			attempt++
+
+			log.WithField(helper.GetRequestIDContext(ctx)).Errorf("failed to read stream %s, retry in %s : %s", stream, delay, err)
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(delay):
+				continue
+			}
+		}
+		attempt = 0
+
+		for _, entry := range entries {
+			d, err := sb.newDelivery(stream, consumerTag, entry)
+			if err != nil {
+				log.WithField(helper.GetRequestIDContext(ctx)).Errorf("invalid entry %s of stream %s is moved to %s : %s", entry.ID, stream, DeadLetterQueue(queue), err)
+				sb.parkEntry(ctx, queue, stream, entry, err)
+				continue
+			}
+
+			// an entry that is not delivered yet stay pending and is claimed again later
+			select {
+			case <-stop:
+				return
+			case messages <- d:
+			}
+		}
+	}
+}
+
+// readEntries move the due retry message, claim the idle pending entry and read new entry of the queue
+func (sb *StreamBroker) readEntries(ctx context.Context, queue, consumerTag string, count int, lastClaim *time.Time) ([]redis.XMessage, error) {
+	stream := fmt.Sprintf(streamKey, queue)
+
+	err := sb.client.XGroupCreateMkStream(ctx, stream, streamGroup, "0").Err()
+	if err != nil && !strings.Contains(err.Error(), "BUSYGROUP") {
+		return nil, err
+	}
+
+	err = moveRetryScript.Run(ctx, sb.client, []string{fmt.Sprintf(streamRetryKey, queue), stream}, time.Now().UnixMilli(), count).Err()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	if time.Since(*lastClaim) >= sb.claimIdle/2 {
+		*lastClaim = time.Now()
+
+		claimed, err := sb.claimPending(ctx, stream, consumerTag, count)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(claimed) > 0 {
+			log.WithField(helper.GetRequestIDContext(ctx)).Warnf("consumer %s claimed %d idle entry of stream %s", consumerTag, len(claimed), stream)
+			for i := range claimed {
+				claimed[i].Values["redelivered"] = "1"
+			}
+			return claimed, nil
+		}
+	}
+
+	streams, err := sb.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    streamGroup,
+		Consumer: consumerTag,
+		Streams:  []string{stream, ">"},
+		Count:    int64(count),
+		Block:    streamBlock,
+	}).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []redis.XMessage{}
+	for _, s := range streams {
+		entries = append(entries, s.Messages...)
+	}
+
+	return entries, nil
+}
+
+// claimPending claim the entry pending on another consumer for longer than the claim idle, e.g. of a crashed worker.
+// XPENDING and XCLAIM are used instead of XAUTOCLAIM, whose reply changed in Redis 7 and is not parsed by go-redis v8
+func (sb *StreamBroker) claimPending(ctx context.Context, stream, consumerTag string, count int) ([]redis.XMessage, error) {
+	pending, err := sb.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: stream,
+		Group:  streamGroup,
+		Idle:   sb.claimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  int64(count),
+	}).Result()
+	if err != nil || len(pending) == 0 {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(pending))
+	for _, entry := range pending {
+		ids = append(ids, entry.ID)
+	}
+
+	// only the entry still idle is claimed, another consumer may have claimed it meanwhile
+	ids, err = sb.client.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    streamGroup,
+		Consumer: consumerTag,
+		MinIdle:  sb.claimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	claimed := make([]redis.XMessage, 0, len(ids))
+	for _, id := range ids {
+		entries, err := sb.client.XRangeN(ctx, stream, id, id, 1).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		// the entry was deleted while pending, there is nothing to deliver
+		if len(entries) == 0 {
+			err = sb.client.XAck(ctx, stream, streamGroup, id).Err()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		claimed = append(claimed, entries[0])
+	}
+
+	return claimed, nil
+}
+
+// keepAlive reset the idle time of the entry while its worker runs, so a worker slower than the claim idle does not
+// get its entry claimed and processed twice by another consumer. The returned function stop it
+func (sb *StreamBroker) keepAlive(ctx context.Context, d amqp.Delivery) func() {
+	acknowledger, ok := d.Acknowledger.(*streamAcknowledger)
+	if !ok {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(sb.claimIdle / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			err := sb.client.XClaimJustID(ctx, &redis.XClaimArgs{
+				Stream:   acknowledger.stream,
+				Group:    streamGroup,
+				Consumer: d.ConsumerTag,
+				Messages: []string{acknowledger.id},
+			}).Err()
+			if err != nil {
+				log.WithField(helper.GetRequestIDContext(ctx)).Errorf("failed to keep entry %s of stream %s alive : %s", acknowledger.id, acknowledger.stream, err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+func (sb *StreamBroker) newDelivery(stream, consumerTag string, entry redis.XMessage) (amqp.Delivery, error) {
+	message, err := decodeStreamMessage(entry)
+	if err != nil {
+		return amqp.Delivery{}, err
+	}
+
+	_, redelivered := entry.Values["redelivered"]
+
+	return amqp.Delivery{
+		Acknowledger: &streamAcknowledger{broker: sb, stream: stream, id: entry.ID, message: message},
+		Headers:      message.Headers,
+		ContentType:  message.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    message.MessageId,
+		Timestamp:    message.Timestamp,
+		Type:         message.Type,
+		ConsumerTag:  consumerTag,
+		Redelivered:  message.Redelivered || redelivered,
+		Exchange:     message.Exchange,
+		RoutingKey:   message.RoutingKey,
+		Body:         message.Body,
+	}, nil
+}
+
+// parkEntry move an entry that can not be decoded to the dead letter queue as is
+func (sb *StreamBroker) parkEntry(ctx context.Context, queue, stream string, entry redis.XMessage, failure error) {
+	raw, _ := entry.Values[streamField].(string)
+
+	err := sb.PublishMessageToDLQ(ctx, queue, amqp.Publishing{
+		Headers: amqp.Table{
+			HeaderFailureReason: failure.Error(),
+			HeaderOriginalQueue: queue,
+			HeaderFailedAt:      time.Now().UTC().Format(time.RFC3339),
+		},
+		MessageId: entry.ID,
+		Body:      []byte(raw),
+	})
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Error(err)
+		return
+	}
+
+	sb.settle(ctx, stream, entry.ID)
+}
+
+func (sb *StreamBroker) PublishMessage(ctx context.Context, queue string, body interface{}) error {
+	message, err := newPublishing(ctx, sb.producer, queue, body)
+	if err != nil {
+		return err
+	}
+
+	return sb.push(ctx, queue, "", queue, message)
+}
+
+// PublishEvent route the event with the topology binding of the exchange, an event without matching binding is unroutable
+func (sb *StreamBroker) PublishEvent(ctx context.Context, exchange, routingKey string, body interface{}) error {
+	message, err := newPublishing(ctx, sb.producer, routingKey, body)
+	if err != nil {
+		return err
+	}
+
+	queues := sb.topology.route(exchange, routingKey)
+	if len(queues) == 0 {
+		return &UnroutableError{
+			Exchange:   exchange,
+			RoutingKey: routingKey,
+			ReplyCode:  amqp.NoRoute,
+			ReplyText:  "NO_ROUTE",
+		}
+	}
+
+	for _, queue := range queues {
+		err = sb.push(ctx, queue, exchange, routingKey, message)
+		if err != nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while publish event %s : %s", routingKey, err.Error())
+			return err
+		}
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("success send event %s : %s", routingKey, message.Body)
+
+	return nil
+}
+
+// PublishMessageToDeathLetter keep the message in the retry set of the queue, it is moved back to the stream
+// by the consumer once the ttl (in millisecond) has expired
+func (sb *StreamBroker) PublishMessageToDeathLetter(ctx context.Context, queue string, message amqp.Publishing, ttl int) error {
+	if sb.isClosed() {
+		return ErrChannelClosed
+	}
+
+	value, err := json.Marshal(newStreamMessage(queue, "", message))
+	if err != nil {
+		return err
+	}
+
+	retryKey := fmt.Sprintf(streamRetryKey, queue)
+	err = sb.client.ZAdd(ctx, retryKey, &redis.Z{
+		Score:  float64(time.Now().Add(time.Duration(ttl) * time.Millisecond).UnixMilli()),
+		Member: string(value),
+	}).Err()
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while publish data : %s", err.Error())
+		return err
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Infof("success send message id %s to %s", message.MessageId, retryKey)
+
+	return nil
+}
+
+func (sb *StreamBroker) PublishMessageToDLQ(ctx context.Context, queue string, message amqp.Publishing) error {
+	return sb.push(ctx, DeadLetterQueue(queue), "", DeadLetterQueue(queue), message)
+}
+
+func (sb *StreamBroker) ListDeadLetter(ctx context.Context, queue string, limit int) ([]DeadLetterMessage, error) {
+	message := []DeadLetterMessage{}
+
+	entries, err := sb.deadLetterEntries(ctx, queue, limit)
+	if err != nil {
+		return message, err
+	}
+
+	for _, entry := range entries {
+		d, err := sb.newDelivery("", "", entry)
+		if err != nil {
+			continue
+		}
+		message = append(message, newDeadLetterMessage(queue, d))
+	}
+
+	return message, nil
+}
+
+// ReplayDeadLetter publish the message back to its original queue, the dead letter entry is deleted once it is published
+func (sb *StreamBroker) ReplayDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error) {
+	selected := selectMessage(messageIds)
+	dlq := fmt.Sprintf(streamKey, DeadLetterQueue(queue))
+	replayed := 0
+
+	entries, err := sb.deadLetterEntries(ctx, queue, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, entry := range entries {
+		d, err := sb.newDelivery("", "", entry)
+		if err != nil || !selected(d.MessageId) {
+			continue
+		}
+
+		original, message := replayMessage(queue, d)
+
+		err = sb.push(ctx, original, "", original, message)
+		if err != nil {
+			return replayed, err
+		}
+
+		err = sb.client.XDel(ctx, dlq, entry.ID).Err()
+		if err != nil {
+			return replayed, err
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+func (sb *StreamBroker) PurgeDeadLetter(ctx context.Context, queue string, messageIds []string) (int, error) {
+	selected := selectMessage(messageIds)
+	dlq := fmt.Sprintf(streamKey, DeadLetterQueue(queue))
+
+	entries, err := sb.deadLetterEntries(ctx, queue, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		// an entry that can not be decoded has no message id, it is only purged with the whole queue
+		d, err := sb.newDelivery("", "", entry)
+		if err != nil && len(messageIds) > 0 {
+			continue
+		}
+		if err == nil && !selected(d.MessageId) {
+			continue
+		}
+		ids = append(ids, entry.ID)
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	purged, err := sb.client.XDel(ctx, dlq, ids...).Result()
+
+	return int(purged), err
+}
+
+// Close stop accepting message and close the client
+func (sb *StreamBroker) Close(ctx context.Context) error {
+	log.WithField(helper.GetRequestIDContext(ctx)).Info("close a connection")
+
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+
+	sb.closed = true
+
+	return sb.client.Close()
+}
+
+func (sb *StreamBroker) isClosed() bool {
+	sb.mutex.Lock()
+	defer sb.mutex.Unlock()
+
+	return sb.closed
+}
+
+func (sb *StreamBroker) deadLetterEntries(ctx context.Context, queue string, limit int) ([]redis.XMessage, error) {
+	dlq := fmt.Sprintf(streamKey, DeadLetterQueue(queue))
+
+	if limit > 0 {
+		return sb.client.XRangeN(ctx, dlq, "-", "+", int64(limit)).Result()
+	}
+
+	return sb.client.XRange(ctx, dlq, "-", "+").Result()
+}
+
+func (sb *StreamBroker) push(ctx context.Context, queue, exchange, routingKey string, message amqp.Publishing) error {
+	if sb.isClosed() {
+		return ErrChannelClosed
+	}
+
+	value, err := json.Marshal(newStreamMessage(exchange, routingKey, message))
+	if err != nil {
+		return err
+	}
+
+	err = sb.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: fmt.Sprintf(streamKey, queue),
+		Values: map[string]interface{}{streamField: string(value)},
+	}).Err()
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("error while publish data : %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// settle acknowledge and delete the entry, a stream has a single consumer group so the entry is not needed anymore
+func (sb *StreamBroker) settle(ctx context.Context, stream, id string) error {
+	pipe := sb.client.TxPipeline()
+	pipe.XAck(ctx, stream, streamGroup, id)
+	pipe.XDel(ctx, stream, id)
+
+	_, err := pipe.Exec(ctx)
+
+	return err
+}
+
+func newStreamMessage(exchange, routingKey string, message amqp.Publishing) streamMessage {
+	return streamMessage{
+		Headers:     message.Headers,
+		ContentType: message.ContentType,
+		MessageId:   message.MessageId,
+		Type:        message.Type,
+		Timestamp:   message.Timestamp,
+		Exchange:    exchange,
+		RoutingKey:  routingKey,
+		Body:        message.Body,
+	}
+}
+
+func decodeStreamMessage(entry redis.XMessage) (streamMessage, error) {
+	var message streamMessage
+
+	raw, ok := entry.Values[streamField].(string)
+	if !ok {
+		return message, fmt.Errorf("entry %s has no %s field", entry.ID, streamField)
+	}
+
+	err := json.Unmarshal([]byte(raw), &message)
+
+	return message, err
+}
+
+// streamAcknowledger settle the entry of one delivery, the delivery tag is not used
+type streamAcknowledger struct {
+	broker  *StreamBroker
+	stream  string
+	id      string
+	message streamMessage
+}
+
+func (a *streamAcknowledger) Ack(tag uint64, multiple bool) error {
+	return a.broker.settle(context.Background(), a.stream, a.id)
+}
+
+// Nack with requeue add the message at the end of the stream again before the entry is settled
+func (a *streamAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	ctx := context.Background()
+
+	if !requeue {
+		return a.broker.settle(ctx, a.stream, a.id)
+	}
+
+	a.message.Redelivered = true
+	value, err := json.Marshal(a.message)
+	if err != nil {
+		return err
+	}
+
+	pipe := a.broker.client.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: a.stream,
+		Values: map[string]interface{}{streamField: string(value)},
+	})
+	pipe.XAck(ctx, a.stream, streamGroup, a.id)
+	pipe.XDel(ctx, a.stream, a.id)
+
+	_, err = pipe.Exec(ctx)
+
+	return err
+}
+
+func (a *streamAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.Nack(tag, false, requeue)
+}
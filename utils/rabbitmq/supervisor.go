@@ -0,0 +1,234 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/galihfebrizki/dbo-api/helper"
+	"github.com/galihfebrizki/dbo-api/utils/lifecycle"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	log "github.com/sirupsen/logrus"
+)
+
+var errConnectionClosed = errors.New("rabbitmq connection is closed")
+
+// ConsumeMessage supervise the consumer until the context is done. The consumer has its own channel, when the channel or the
+// connection is closed or the broker cancel the consumer, the channel is reopened with jittered backoff, the queue is
+// declared again and the consumer restarted. It return once every worker is done
+func (mq *RabbitMQ) ConsumeMessage(ctx context.Context, appName, consumerTag string, consumer Consumer) error {
+	workCtx := lifecycle.WithoutCancel(ctx)
+	consumerTag = fmt.Sprintf("%s|%s|%s", appName, consumer.Queue, consumerTag)
+	concurrency := consumer.Options.concurrency(mq.concurrency)
+	prefetch := consumer.Options.prefetch(concurrency)
+
+	for attempt := 0; ; {
+		channel, messages, closed, cancelled, err := mq.startConsumer(ctx, consumer.Queue, consumerTag, prefetch)
+		if err != nil {
+			delay := mq.reconnectDelay(attempt)
+			attempt++
+
+			log.WithField(helper.GetRequestIDContext(ctx)).Errorf("failed to start consumer %s, retry in %s : %s", consumerTag, delay, err)
+
+			select {
+			case <-ctx.Done():
+				log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Got exit signal")
+				return nil
+			case <-time.After(delay):
+				continue
+			}
+		}
+		attempt = 0
+
+		limiter := consumer.Options.limiter()
+
+		done := make(chan bool)
+		wg := sync.WaitGroup{}
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for message := range messages {
+					if limiter != nil {
+						<-limiter.C
+					}
+					handleMessage(workCtx, mq, consumer, message)
+				}
+			}()
+		}
+		go func() {
+			wg.Wait()
+			if limiter != nil {
+				limiter.Stop()
+			}
+			close(done)
+		}()
+
+		log.WithField(helper.GetRequestIDContext(ctx)).Infof("Consumer %s already started", consumerTag)
+
+		select {
+		case <-ctx.Done():
+			log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Got exit signal")
+
+			// stop receiving message from queue, prefetched message is processed before the channel is closed
+			err = channel.Cancel(consumerTag, false)
+			if err != nil {
+				log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to cancel the consumer")
+			}
+
+			<-done
+			channel.Close()
+
+			log.WithField(helper.GetRequestIDContext(ctx)).Infoln("Stopped receiving message from queue")
+
+			return nil
+		case err := <-closed:
+			log.WithField(helper.GetRequestIDContext(ctx)).Warnf("channel of consumer %s closed, restarting : %v", consumerTag, err)
+		case <-cancelled:
+			log.WithField(helper.GetRequestIDContext(ctx)).Warnf("consumer %s cancelled by the broker, restarting", consumerTag)
+		}
+
+		// the delivery channel is closed together with the channel or the consumer
+		<-done
+		channel.Close()
+	}
+}
+
+// startConsumer open the consumer channel and declare the queue, the close and cancel notification is registered
+// before consuming so no notification is missed
+func (mq *RabbitMQ) startConsumer(ctx context.Context, queueName, consumerTag string, prefetch int) (*amqp.Channel, <-chan amqp.Delivery, chan *amqp.Error, chan string, error) {
+	channel, err := mq.consumerChannel(ctx)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	closed := channel.NotifyClose(make(chan *amqp.Error, 1))
+	cancelled := channel.NotifyCancel(make(chan string, 1))
+
+	//QueueDeclare declares a queue to hold messages and deliver to consumers. Declaring creates a queue if it doesn't already exist, or ensures that an existing queue matches the same parameters.
+	queue, err := channel.QueueDeclare(
+		queueName, // name
+		true,      // durable
+		false,     // delete when unused
+		false,     // exclusive
+		false,     // no-wait
+		nil,       // arguments
+	)
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to declare a queue")
+		channel.Close()
+		return nil, nil, nil, nil, err
+	}
+
+	//Qos controls how many messages or how many bytes the server will try to keep on the network for consumers before receiving delivery acks. The intent of Qos is to make sure the network buffers stay full between the server and client.
+	err = channel.Qos(
+		prefetch, // prefetch count
+		0,        // prefetch size
+		false,    // global
+	)
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to set QOS")
+		channel.Close()
+		return nil, nil, nil, nil, err
+	}
+
+	messages, err := channel.Consume(
+		queue.Name,  // queue
+		consumerTag, // consumer
+		false,       // auto-ack
+		false,       // exclusive
+		false,       // no-local
+		false,       // no-wait
+		nil,         // args
+	)
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Error("failed to register a consumer")
+		channel.Close()
+		return nil, nil, nil, nil, err
+	}
+
+	return channel, messages, closed, cancelled, nil
+}
+
+// consumerChannel open a dedicated channel on the current connection, a channel error only affect its own consumer
+func (mq *RabbitMQ) consumerChannel(ctx context.Context) (*amqp.Channel, error) {
+	mq.mutex.Lock()
+	defer mq.mutex.Unlock()
+
+	if mq.closed {
+		return nil, errConnectionClosed
+	}
+
+	err := mq.ensureConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return mq.connection.Channel()
+}
+
+// watchConnection reconnect with jittered backoff when the connection is closed by the broker or the network.
+// Consumer notice the close on their own channel and reopen it on the new connection
+func (mq *RabbitMQ) watchConnection(ctx context.Context, connection *amqp.Connection, closed chan *amqp.Error) {
+	err, ok := <-closed
+	if !ok {
+		// closed on purpose
+		return
+	}
+
+	log.WithField(helper.GetRequestIDContext(ctx)).Warnf("connection closed, reconnecting : %v", err)
+
+	for attempt := 0; ; attempt++ {
+		mq.mutex.Lock()
+
+		// stopped, or already reopened by a publisher or a consumer
+		if mq.closed || (mq.connection != connection && !mq.connection.IsClosed()) {
+			mq.mutex.Unlock()
+			return
+		}
+
+		reconnectErr := mq.Connect(ctx, mq.url)
+		mq.mutex.Unlock()
+
+		if reconnectErr == nil {
+			log.WithField(helper.GetRequestIDContext(ctx)).Info("connection reopened")
+			return
+		}
+
+		delay := mq.reconnectDelay(attempt)
+		log.WithField(helper.GetRequestIDContext(ctx)).Errorf("failed to reconnect, retry in %s : %s", delay, reconnectErr)
+		time.Sleep(delay)
+	}
+}
+
+// reconnectDelay jittered wait before the next reconnect attempt
+func (mq *RabbitMQ) reconnectDelay(attempt int) time.Duration {
+	return jitterDelay(mq.reconnectMin, mq.reconnectMax, attempt)
+}
+
+// jitterDelay double the wait after every failed attempt up to the maximum, the wait is randomized
+// between half and full so every instance does not reconnect at the same time
+func jitterDelay(minDelay, maxDelay time.Duration, attempt int) time.Duration {
+	if minDelay <= 0 {
+		minDelay = time.Second
+	}
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+
+	delay := minDelay
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	half := int64(delay / 2)
+
+	return time.Duration(half + rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(half+1))
+}
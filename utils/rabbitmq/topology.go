@@ -0,0 +1,154 @@
+package rabbitmq
+
+import (
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Exchange kind
+const (
+	ExchangeDirect = "direct"
+	ExchangeTopic  = "topic"
+	ExchangeFanout = "fanout"
+)
+
+type Exchange struct {
+	Name    string
+	Kind    string
+	Durable bool
+	Args    amqp.Table
+}
+
+type Queue struct {
+	Name    string
+	Durable bool
+	Args    amqp.Table
+}
+
+// Binding route message of the exchange to the queue, on a topic exchange the routing key is a pattern
+// where * match exactly one word and # match zero or more word, e.g. order.*
+type Binding struct {
+	Exchange   string
+	Queue      string
+	RoutingKey string
+	Args       amqp.Table
+}
+
+// Topology exchange, queue and binding declared on every connect, so they exist again after the broker lost them
+type Topology struct {
+	Exchanges []Exchange
+	Queues    []Queue
+	Bindings  []Binding
+}
+
+// Subscribe bind the queue to the exchange with every pattern
+func Subscribe(exchange, queue string, patterns ...string) []Binding {
+	bindings := make([]Binding, 0, len(patterns))
+	for _, pattern := range patterns {
+		bindings = append(bindings, Binding{
+			Exchange:   exchange,
+			Queue:      queue,
+			RoutingKey: pattern,
+		})
+	}
+
+	return bindings
+}
+
+// declare declare the exchange first, then the queue and the binding between them
+func (t Topology) declare(channel *amqp.Channel) error {
+	for _, e := range t.Exchanges {
+		err := channel.ExchangeDeclare(
+			e.Name,
+			e.Kind,
+			e.Durable,
+			false, // auto delete
+			false, // internal
+			false, // no-wait
+			e.Args,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, q := range t.Queues {
+		_, err := channel.QueueDeclare(
+			q.Name,
+			q.Durable,
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			q.Args,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, b := range t.Bindings {
+		err := channel.QueueBind(
+			b.Queue,
+			b.RoutingKey,
+			b.Exchange,
+			false, // no-wait
+			b.Args,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// route queue bound to the exchange with a binding matching the routing key, every queue get one copy
+func (t Topology) route(exchange, routingKey string) []string {
+	kind := ExchangeDirect
+	for _, e := range t.Exchanges {
+		if e.Name == exchange {
+			kind = e.Kind
+		}
+	}
+
+	queues := []string{}
+	matched := map[string]bool{}
+	for _, b := range t.Bindings {
+		if b.Exchange != exchange || matched[b.Queue] {
+			continue
+		}
+
+		if kind == ExchangeFanout || (kind == ExchangeTopic && matchTopic(b.RoutingKey, routingKey)) || b.RoutingKey == routingKey {
+			matched[b.Queue] = true
+			queues = append(queues, b.Queue)
+		}
+	}
+
+	return queues
+}
+
+// matchTopic match the routing key with the topic pattern, * match exactly one word and # match zero or more word
+func matchTopic(pattern, routingKey string) bool {
+	return matchWord(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+func matchWord(pattern, word []string) bool {
+	if len(pattern) == 0 {
+		return len(word) == 0
+	}
+
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(word); i++ {
+			if matchWord(pattern[1:], word[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(word) > 0 && matchWord(pattern[1:], word[1:])
+	default:
+		return len(word) > 0 && pattern[0] == word[0] && matchWord(pattern[1:], word[1:])
+	}
+}
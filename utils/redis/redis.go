@@ -13,6 +13,7 @@ import (
 
 type Iredis interface {
 	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error)
 	Get(ctx context.Context, key string, dest interface{}) error
 	LPush(ctx context.Context, key string, value interface{}) error
 	LPop(ctx context.Context, key string, dest interface{}) error
@@ -23,6 +24,7 @@ type Iredis interface {
 	LTrim(ctx context.Context, key string, start, stop int64) error
 	Del(ctx context.Context, key string) error
 	Ping(ctx context.Context) error
+	Close() error
 }
 
 type Redis struct {
@@ -66,6 +68,23 @@ func (rdb *Redis) Set(ctx context.Context, key string, value interface{}, ttl ti
 	return err
 }
 
+// SetNX set the key only when it does not exist, return false when the key already exist
+func (rdb *Redis) SetNX(ctx context.Context, key string, value interface{}, ttl time.Duration) (bool, error) {
+	val, err := json.Marshal(value)
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Debug(err.Error())
+		return false, err
+	}
+
+	isSet, err := rdb.redis.SetNX(ctx, key, string(val), ttl).Result()
+	if err != nil {
+		log.WithField(helper.GetRequestIDContext(ctx)).Debug(err.Error())
+		return false, err
+	}
+
+	return isSet, nil
+}
+
 func (rdb *Redis) Get(ctx context.Context, key string, dest interface{}) error {
 	val, err := rdb.redis.Get(ctx, key).Result()
 	if err != nil {
@@ -195,3 +214,8 @@ func (rdb *Redis) Ping(ctx context.Context) error {
 
 	return nil
 }
+
+// Close close every connection of the pool
+func (rdb *Redis) Close() error {
+	return rdb.redis.Close()
+}
@@ -9,6 +9,12 @@ import (
 var nodeOrder *snowflake.Node
 var nodeOrderItem *snowflake.Node
 var nodeUser *snowflake.Node
+var nodeExport *snowflake.Node
+var nodeInvoice *snowflake.Node
+var nodeAddress *snowflake.Node
+var nodeShipment *snowflake.Node
+var nodePayment *snowflake.Node
+var nodeOutbox *snowflake.Node
 
 // InitSnowflakeOrder initiate Snowflake node singleton.
 func InitSnowflakeOrder() error {
@@ -93,3 +99,171 @@ func InitSnowflakeUser() error {
 func GenerateSnowflakeUser() string {
 	return nodeUser.Generate().String()
 }
+
+// InitSnowflakeExport initiate Snowflake node singleton.
+func InitSnowflakeExport() error {
+	var err error
+
+	// Get node number from env
+	nodeNo := config.Get().Snowflake.Export
+	if nodeNo > 0 {
+		// Create snowflake node
+		n, err := snowflake.NewNode(nodeNo)
+		if err != nil {
+			return err
+		}
+		// Set node
+		nodeExport = n
+	}
+
+	if nodeExport == nil {
+		return err
+	}
+
+	return nil
+}
+
+// GenerateSnowflakeExport generate Snowflake ID
+func GenerateSnowflakeExport() string {
+	return nodeExport.Generate().String()
+}
+
+// InitSnowflakeInvoice initiate Snowflake node singleton.
+func InitSnowflakeInvoice() error {
+	var err error
+
+	// Get node number from env
+	nodeNo := config.Get().Snowflake.Invoice
+	if nodeNo > 0 {
+		// Create snowflake node
+		n, err := snowflake.NewNode(nodeNo)
+		if err != nil {
+			return err
+		}
+		// Set node
+		nodeInvoice = n
+	}
+
+	if nodeInvoice == nil {
+		return err
+	}
+
+	return nil
+}
+
+// GenerateSnowflakeInvoice generate Snowflake ID
+func GenerateSnowflakeInvoice() string {
+	return nodeInvoice.Generate().String()
+}
+
+// InitSnowflakeAddress initiate Snowflake node singleton.
+func InitSnowflakeAddress() error {
+	var err error
+
+	// Get node number from env
+	nodeNo := config.Get().Snowflake.Address
+	if nodeNo > 0 {
+		// Create snowflake node
+		n, err := snowflake.NewNode(nodeNo)
+		if err != nil {
+			return err
+		}
+		// Set node
+		nodeAddress = n
+	}
+
+	if nodeAddress == nil {
+		return err
+	}
+
+	return nil
+}
+
+// GenerateSnowflakeAddress generate Snowflake ID
+func GenerateSnowflakeAddress() string {
+	return nodeAddress.Generate().String()
+}
+
+// InitSnowflakeShipment initiate Snowflake node singleton.
+func InitSnowflakeShipment() error {
+	var err error
+
+	// Get node number from env
+	nodeNo := config.Get().Snowflake.Shipment
+	if nodeNo > 0 {
+		// Create snowflake node
+		n, err := snowflake.NewNode(nodeNo)
+		if err != nil {
+			return err
+		}
+		// Set node
+		nodeShipment = n
+	}
+
+	if nodeShipment == nil {
+		return err
+	}
+
+	return nil
+}
+
+// GenerateSnowflakeShipment generate Snowflake ID
+func GenerateSnowflakeShipment() string {
+	return nodeShipment.Generate().String()
+}
+
+// InitSnowflakePayment initiate Snowflake node singleton.
+func InitSnowflakePayment() error {
+	var err error
+
+	// Get node number from env
+	nodeNo := config.Get().Snowflake.Payment
+	if nodeNo > 0 {
+		// Create snowflake node
+		n, err := snowflake.NewNode(nodeNo)
+		if err != nil {
+			return err
+		}
+		// Set node
+		nodePayment = n
+	}
+
+	if nodePayment == nil {
+		return err
+	}
+
+	return nil
+}
+
+// GenerateSnowflakePayment generate Snowflake ID
+func GenerateSnowflakePayment() string {
+	return nodePayment.Generate().String()
+}
+
+// InitSnowflakeOutbox initiate Snowflake node singleton.
+func InitSnowflakeOutbox() error {
+	var err error
+
+	// Get node number from env
+	nodeNo := config.Get().Snowflake.Outbox
+	if nodeNo > 0 {
+		// Create snowflake node
+		n, err := snowflake.NewNode(nodeNo)
+		if err != nil {
+			return err
+		}
+		// Set node
+		nodeOutbox = n
+	}
+
+	if nodeOutbox == nil {
+		return err
+	}
+
+	return nil
+}
+
+// GenerateSnowflakeOutbox generate Snowflake ID
+func GenerateSnowflakeOutbox() string {
+	return nodeOutbox.Generate().String()
+}
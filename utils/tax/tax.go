@@ -0,0 +1,217 @@
+package tax
+
+import (
+	"sort"
+	"strings"
+)
+
+// RateScale rate are stored in basis point, 1100 = 11%
+const RateScale = 10000
+
+type Rounding string
+
+const (
+	RoundHalfUp   Rounding = "half_up"
+	RoundHalfEven Rounding = "half_even"
+	RoundDown     Rounding = "down"
+	RoundUp       Rounding = "up"
+)
+
+type Level string
+
+const (
+	// LevelLine round tax on every line, order tax is the sum of the lines
+	LevelLine Level = "line"
+	// LevelOrder round tax once per rate on the order, then allocate it back to the lines
+	LevelOrder Level = "order"
+)
+
+type Class struct {
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	RateBps        int64  `json:"rate_bps"`
+	PriceInclusive bool   `json:"price_inclusive"`
+}
+
+type Line struct {
+	// Amount line price after discount as written on the price list
+	Amount int64
+	Class  Class
+}
+
+type LineResult struct {
+	Base  int64 `json:"base"`
+	Tax   int64 `json:"tax"`
+	Total int64 `json:"total"`
+}
+
+type Result struct {
+	Lines []LineResult `json:"lines"`
+	Base  int64        `json:"base"`
+	Tax   int64        `json:"tax"`
+	Total int64        `json:"total"`
+}
+
+type Calculator struct {
+	Rounding Rounding
+	Level    Level
+}
+
+func NewCalculator(rounding string, level string) Calculator {
+	c := Calculator{
+		Rounding: Rounding(strings.ToLower(rounding)),
+		Level:    Level(strings.ToLower(level)),
+	}
+
+	switch c.Rounding {
+	case RoundHalfUp, RoundHalfEven, RoundDown, RoundUp:
+	default:
+		c.Rounding = RoundHalfUp
+	}
+
+	if c.Level != LevelOrder {
+		c.Level = LevelLine
+	}
+
+	return c
+}
+
+// Calculate compute base (DPP), tax and total for every line and the whole order
+func (c Calculator) Calculate(lines []Line) Result {
+	result := Result{
+		Lines: make([]LineResult, len(lines)),
+	}
+
+	if c.Level == LevelOrder {
+		c.allocateOrderTax(lines, result.Lines)
+	} else {
+		for i, line := range lines {
+			result.Lines[i] = lineResult(line, c.lineTax(line.Amount, line.Class))
+		}
+	}
+
+	for _, line := range result.Lines {
+		result.Base += line.Base
+		result.Tax += line.Tax
+		result.Total += line.Total
+	}
+
+	return result
+}
+
+// lineTax tax part of amount, for inclusive price the tax is extracted from the amount
+func (c Calculator) lineTax(amount int64, class Class) int64 {
+	if class.RateBps <= 0 {
+		return 0
+	}
+
+	if class.PriceInclusive {
+		return Round(amount*class.RateBps, RateScale+class.RateBps, c.Rounding)
+	}
+
+	return Round(amount*class.RateBps, RateScale, c.Rounding)
+}
+
+func lineResult(line Line, tax int64) LineResult {
+	if line.Class.PriceInclusive {
+		return LineResult{
+			Base:  line.Amount - tax,
+			Tax:   tax,
+			Total: line.Amount,
+		}
+	}
+
+	return LineResult{
+		Base:  line.Amount,
+		Tax:   tax,
+		Total: line.Amount + tax,
+	}
+}
+
+// allocateOrderTax round the tax once per tax class and spread it to the lines using largest remainder,
+// so the line tax always add up to the order tax
+func (c Calculator) allocateOrderTax(lines []Line, results []LineResult) {
+	groups := map[Class][]int{}
+	for i, line := range lines {
+		groups[line.Class] = append(groups[line.Class], i)
+	}
+
+	for class, indexes := range groups {
+		var amount int64
+		for _, i := range indexes {
+			amount += lines[i].Amount
+		}
+
+		total := c.lineTax(amount, class)
+
+		type share struct {
+			index     int
+			remainder int64
+		}
+
+		shares := make([]share, 0, len(indexes))
+		allocated := int64(0)
+
+		for _, i := range indexes {
+			var tax, remainder int64
+			if amount != 0 {
+				tax = total * lines[i].Amount / amount
+				remainder = total * lines[i].Amount % amount
+			}
+
+			results[i] = lineResult(lines[i], tax)
+			allocated += tax
+			shares = append(shares, share{index: i, remainder: remainder})
+		}
+
+		sort.SliceStable(shares, func(a, b int) bool {
+			return shares[a].remainder > shares[b].remainder
+		})
+
+		for j := 0; allocated < total && len(shares) > 0; j++ {
+			i := shares[j%len(shares)].index
+			results[i] = lineResult(lines[i], results[i].Tax+1)
+			allocated++
+		}
+	}
+}
+
+// Round divide numerator by denominator (both positive) using the rounding mode
+func Round(numerator, denominator int64, mode Rounding) int64 {
+	if denominator == 0 {
+		return 0
+	}
+
+	negative := (numerator < 0) != (denominator < 0)
+	if numerator < 0 {
+		numerator = -numerator
+	}
+	if denominator < 0 {
+		denominator = -denominator
+	}
+
+	quotient := numerator / denominator
+	remainder := numerator % denominator
+
+	if remainder != 0 {
+		switch mode {
+		case RoundUp:
+			quotient++
+		case RoundDown:
+		case RoundHalfEven:
+			if remainder*2 > denominator || (remainder*2 == denominator && quotient%2 == 1) {
+				quotient++
+			}
+		default:
+			if remainder*2 >= denominator {
+				quotient++
+			}
+		}
+	}
+
+	if negative {
+		return -quotient
+	}
+
+	return quotient
+}
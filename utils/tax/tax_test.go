@@ -0,0 +1,185 @@
+package tax
+
+import "testing"
+
+var (
+	ppn11    = Class{Code: "PPN11", RateBps: 1100}
+	ppn11Inc = Class{Code: "PPN11_INC", RateBps: 1100, PriceInclusive: true}
+	ppn12    = Class{Code: "PPN12", RateBps: 1200}
+	ppn12Inc = Class{Code: "PPN12_INC", RateBps: 1200, PriceInclusive: true}
+	exempt   = Class{Code: "EXEMPT"}
+)
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		name        string
+		numerator   int64
+		denominator int64
+		mode        Rounding
+		want        int64
+	}{
+		{"half up on half", 25, 10, RoundHalfUp, 3},
+		{"half up below half", 24, 10, RoundHalfUp, 2},
+		{"half even on half to even", 25, 10, RoundHalfEven, 2},
+		{"half even on half to odd", 35, 10, RoundHalfEven, 4},
+		{"half even above half", 26, 10, RoundHalfEven, 3},
+		{"down", 29, 10, RoundDown, 2},
+		{"up", 21, 10, RoundUp, 3},
+		{"up exact", 20, 10, RoundUp, 2},
+		{"negative half up", -25, 10, RoundHalfUp, -3},
+		{"negative up", -21, 10, RoundUp, -3},
+		{"negative down", -29, 10, RoundDown, -2},
+		{"zero denominator", 10, 0, RoundHalfUp, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Round(tt.numerator, tt.denominator, tt.mode)
+			if got != tt.want {
+				t.Errorf("Round(%d, %d, %s) = %d, want %d", tt.numerator, tt.denominator, tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding Rounding
+		line     Line
+		want     LineResult
+	}{
+		{"ppn 11 exclusive", RoundHalfUp, Line{Amount: 10000, Class: ppn11}, LineResult{Base: 10000, Tax: 1100, Total: 11100}},
+		{"ppn 11 exclusive half up", RoundHalfUp, Line{Amount: 12345, Class: ppn11}, LineResult{Base: 12345, Tax: 1358, Total: 13703}},
+		{"ppn 11 exclusive down", RoundDown, Line{Amount: 12345, Class: ppn11}, LineResult{Base: 12345, Tax: 1357, Total: 13702}},
+		{"ppn 11 exclusive half up on half", RoundHalfUp, Line{Amount: 150, Class: ppn11}, LineResult{Base: 150, Tax: 17, Total: 167}},
+		{"ppn 11 exclusive half even on half", RoundHalfEven, Line{Amount: 150, Class: ppn11}, LineResult{Base: 150, Tax: 16, Total: 166}},
+		{"ppn 11 inclusive", RoundHalfUp, Line{Amount: 11100, Class: ppn11Inc}, LineResult{Base: 10000, Tax: 1100, Total: 11100}},
+		{"ppn 11 inclusive half up", RoundHalfUp, Line{Amount: 10000, Class: ppn11Inc}, LineResult{Base: 9009, Tax: 991, Total: 10000}},
+		{"ppn 11 inclusive down", RoundDown, Line{Amount: 10000, Class: ppn11Inc}, LineResult{Base: 9010, Tax: 990, Total: 10000}},
+		{"ppn 12 exclusive", RoundHalfUp, Line{Amount: 10000, Class: ppn12}, LineResult{Base: 10000, Tax: 1200, Total: 11200}},
+		{"ppn 12 exclusive half up", RoundHalfUp, Line{Amount: 12345, Class: ppn12}, LineResult{Base: 12345, Tax: 1481, Total: 13826}},
+		{"ppn 12 exclusive up", RoundUp, Line{Amount: 12345, Class: ppn12}, LineResult{Base: 12345, Tax: 1482, Total: 13827}},
+		{"ppn 12 inclusive", RoundHalfUp, Line{Amount: 11200, Class: ppn12Inc}, LineResult{Base: 10000, Tax: 1200, Total: 11200}},
+		{"ppn 12 inclusive half up", RoundHalfUp, Line{Amount: 10000, Class: ppn12Inc}, LineResult{Base: 8929, Tax: 1071, Total: 10000}},
+		{"exempt", RoundHalfUp, Line{Amount: 10000, Class: exempt}, LineResult{Base: 10000, Tax: 0, Total: 10000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Calculator{Rounding: tt.rounding, Level: LevelLine}.Calculate([]Line{tt.line})
+			if result.Lines[0] != tt.want {
+				t.Errorf("line = %+v, want %+v", result.Lines[0], tt.want)
+			}
+			if result.Base != tt.want.Base || result.Tax != tt.want.Tax || result.Total != tt.want.Total {
+				t.Errorf("order = %d/%d/%d, want %d/%d/%d", result.Base, result.Tax, result.Total, tt.want.Base, tt.want.Tax, tt.want.Total)
+			}
+		})
+	}
+}
+
+func TestCalculateLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     Level
+		lines     []Line
+		wantTax   []int64
+		wantTotal int64
+	}{
+		{
+			name:      "line level round every line",
+			level:     LevelLine,
+			lines:     []Line{{Amount: 333, Class: ppn11}, {Amount: 333, Class: ppn11}, {Amount: 333, Class: ppn11}},
+			wantTax:   []int64{37, 37, 37},
+			wantTotal: 1110,
+		},
+		{
+			name:      "order level round once and allocate",
+			level:     LevelOrder,
+			lines:     []Line{{Amount: 333, Class: ppn11}, {Amount: 333, Class: ppn11}, {Amount: 333, Class: ppn11}},
+			wantTax:   []int64{37, 37, 36},
+			wantTotal: 1109,
+		},
+		{
+			name:      "order level round per class",
+			level:     LevelOrder,
+			lines:     []Line{{Amount: 333, Class: ppn11}, {Amount: 333, Class: ppn12}, {Amount: 667, Class: ppn11}},
+			wantTax:   []int64{37, 40, 73},
+			wantTotal: 1483,
+		},
+		{
+			name:      "order level inclusive",
+			level:     LevelOrder,
+			lines:     []Line{{Amount: 3700, Class: ppn11Inc}, {Amount: 3700, Class: ppn11Inc}, {Amount: 3700, Class: ppn11Inc}},
+			wantTax:   []int64{367, 367, 366},
+			wantTotal: 11100,
+		},
+		{
+			name:      "order level exempt",
+			level:     LevelOrder,
+			lines:     []Line{{Amount: 1000, Class: exempt}, {Amount: 1000, Class: ppn12}},
+			wantTax:   []int64{0, 120},
+			wantTotal: 2120,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calculator := Calculator{Rounding: RoundHalfUp, Level: tt.level}
+			result := calculator.Calculate(tt.lines)
+
+			var lineTax int64
+			for i, line := range result.Lines {
+				if line.Tax != tt.wantTax[i] {
+					t.Errorf("line %d tax = %d, want %d", i, line.Tax, tt.wantTax[i])
+				}
+				if line.Base+line.Tax != line.Total {
+					t.Errorf("line %d base %d + tax %d != total %d", i, line.Base, line.Tax, line.Total)
+				}
+				lineTax += line.Tax
+			}
+
+			if lineTax != result.Tax {
+				t.Errorf("line tax sum %d != order tax %d", lineTax, result.Tax)
+			}
+			if result.Total != tt.wantTotal {
+				t.Errorf("order total = %d, want %d", result.Total, tt.wantTotal)
+			}
+
+			if tt.level == LevelOrder {
+				// the order tax is the tax of every class rounded once
+				amount := map[Class]int64{}
+				for _, line := range tt.lines {
+					amount[line.Class] += line.Amount
+				}
+
+				var want int64
+				for class, sum := range amount {
+					want += calculator.lineTax(sum, class)
+				}
+				if result.Tax != want {
+					t.Errorf("order tax = %d, want %d", result.Tax, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewCalculator(t *testing.T) {
+	tests := []struct {
+		rounding string
+		level    string
+		want     Calculator
+	}{
+		{"HALF_EVEN", "ORDER", Calculator{Rounding: RoundHalfEven, Level: LevelOrder}},
+		{"down", "line", Calculator{Rounding: RoundDown, Level: LevelLine}},
+		{"unknown", "unknown", Calculator{Rounding: RoundHalfUp, Level: LevelLine}},
+	}
+
+	for _, tt := range tests {
+		got := NewCalculator(tt.rounding, tt.level)
+		if got != tt.want {
+			t.Errorf("NewCalculator(%s, %s) = %+v, want %+v", tt.rounding, tt.level, got, tt.want)
+		}
+	}
+}